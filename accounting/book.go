@@ -4,73 +4,112 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"log"
-	"math/big"
 	"sort"
 	"text/tabwriter"
 	"time"
 
+	"github.com/fho/cryptotax/log"
 	"github.com/fho/cryptotax/math"
 	"github.com/fho/cryptotax/transaction"
 )
 
-const TaxFreeAfter = time.Hour * 24 * 365
-
 const TimeFormat = "02.01.2006"
 
 type TaxRecord struct {
 	Currency            transaction.Currency
 	BuyTs               time.Time
 	SellTs              time.Time
-	SellPrice           *big.Float
-	BuyPrice            *big.Float
-	AdvertisingCosts    *big.Float //Werbungskosten
+	SellPrice           math.Decimal
+	BuyPrice            math.Decimal
+	AdvertisingCosts    math.Decimal //Werbungskosten
 	HoldLongerThenAYear bool
 	TaxYear             int
 }
 
+// Book contains the transactions for that the taxes are calculated.
+// It is not modified by Calculate, the calculation can be repeated.
 type Book struct {
-	records []*credit
-	buys    []*transaction.Tx
-	sells   []*transaction.Tx
-	taxYear int
+	txs []*transaction.Tx // in the order they are processed
+}
+
+// Result is the outcome of Book.Calculate.
+type Result struct {
+	// Lots contains a lot per acquisition, in the order they are consumed
+	Lots []*Lot
+	// Matches contains the sold quantities of the lots, in the order
+	// they were recorded
+	Matches    []*Match
+	TaxRecords []*TaxRecord
+	Warnings   []string
+	// Unmatched contains the sold quantities for that no acquisition
+	// exists
+	Unmatched []*UnmatchedSell
+
+	opts Options
+	dust map[transaction.Currency]*dust
+	// last is the timestamp of the last booked transaction
+	last time.Time
 }
 
-type credit struct {
-	balance *big.Float // remaining
-	buyTx   *transaction.Tx
-	sells   []*sell
+// Lot is the quantity of a currency that was acquired by a transaction.
+type Lot struct {
+	Currency transaction.Currency
+	Quantity math.Decimal
+	Balance  math.Decimal // remaining
+	Cost     math.Decimal // cost basis of Balance in €
+	BuyTx    *transaction.Tx
+	// Acquired is the start of the holding period, the timestamp of
+	// BuyTx or the acquisition date of the donor of a gift
+	Acquired time.Time
+	Matches  []*Match
 }
 
-type sell struct {
-	profit                 *big.Float
-	quantity               *big.Float
-	holdTime               time.Duration
-	tx                     *transaction.Tx
-	paidWithCryptocurrency bool
+// Match is the part of a sell or trade that was taken from a Lot.
+type Match struct {
+	Lot      *Lot
+	Quantity math.Decimal
+	Cost     math.Decimal // cost basis of Quantity in €
+	Proceeds math.Decimal // € received for Quantity
+	Profit   math.Decimal
+	HoldTime time.Duration
+	Tx       *transaction.Tx
+	// PaidWithCryptocurrency is true when the quantity was exchanged
+	// for another cryptocurrency. The profit is realized at the € value
+	// of the trade, if it is unknown the cost basis is transferred to
+	// the acquired lot and no profit is realized
+	PaidWithCryptocurrency bool
+	// Gifted is true when the quantity was given away, it is not taxed
+	Gifted bool
+	// Fee is true when the quantity was paid as fee of the transaction,
+	// e.g. a network fee of a transfer, it is not taxed
+	Fee bool
+	// Lost is true when the quantity was lost or stolen, it is not taxed
+	Lost bool
 }
 
-func (s *sell) HoldTimeIsLessThenYear() bool {
-	return s.holdTime <= TaxFreeAfter
+// HoldTimeIsLessThenYear returns true if the quantity was not held for
+// more than a year, the profit is taxable.
+func (m *Match) HoldTimeIsLessThenYear() bool {
+	return m.Tx.Timestamp.Before(TaxFreeTs(m.Lot.Acquired))
 }
 
-func (s *sell) String() string {
-	return fmt.Sprintf("%s %s%s @ %s for %f, taxed: %v, profit: %s",
-		s.tx.Timestamp.Format(time.RFC3339), s.quantity.String(), s.tx.Currency,
-		s.tx.Exchange, s.tx.PriceNoFees(), s.HoldTimeIsLessThenYear(),
-		s.profit.String())
+func (m *Match) String() string {
+	return fmt.Sprintf("%s %s%s @ %s for %.2f€, taxed: %v, profit: %.2f€",
+		m.Tx.Timestamp.Format(time.RFC3339), m.Quantity, m.Lot.Currency,
+		m.Tx.Account(), m.Proceeds, m.HoldTimeIsLessThenYear(),
+		m.Profit)
 }
 
-func (c *credit) String() string {
-	res := fmt.Sprintf("%s\n  Balance: %s\n", c.buyTx, c.balance.String())
+func (l *Lot) String() string {
+	res := fmt.Sprintf("%s\n  Balance: %s\n", l.BuyTx, l.Balance)
 
-	if len(c.sells) > 0 {
+	if len(l.Matches) > 0 {
 		res += "  Sells:\n"
 	}
 
-	for i, s := range c.sells {
-		res += "    " + s.String()
-		if i+1 < len(c.sells) {
+	for i, m := range l.Matches {
+		res += "    " + m.String()
+		if i+1 < len(l.Matches) {
 			res += "\n"
 		}
 	}
@@ -78,221 +117,469 @@ func (c *credit) String() string {
 	return res
 }
 
-func NewBook(records []*transaction.Tx, taxYear int) (*Book, error) {
-	b := Book{
-		taxYear: taxYear,
-	}
+// typeOrder defines the processing order of transactions with the same
+// timestamp, acquisitions are processed first
+var typeOrder = map[transaction.Type]int{
+	transaction.Buy:          0,
+	transaction.Deposit:      0,
+	transaction.Income:       0,
+	transaction.GiftReceived: 0,
+	transaction.Sell:         1,
+	transaction.Withdrawal:   1,
+	transaction.GiftSent:     1,
+	transaction.Lost:         1,
+}
+
+func NewBook(records []*transaction.Tx) (*Book, error) {
+	var b Book
 
 	for _, rec := range records {
-		if rec.Type == transaction.Buy {
-			b.buys = append(b.buys, rec)
-			continue
+		if _, exist := typeOrder[rec.Type]; !exist {
+			return nil, fmt.Errorf("unsupported transaction type: %s", rec.Type)
 		}
 
-		if rec.Type == transaction.Sell {
-			b.sells = append(b.sells, rec)
-			continue
+		b.txs = append(b.txs, rec)
+	}
+
+	sort.SliceStable(b.txs, func(i, j int) bool {
+		x, y := b.txs[i], b.txs[j]
+
+		if !x.Timestamp.Equal(y.Timestamp) {
+			return x.Timestamp.Before(y.Timestamp)
 		}
 
-		return nil, fmt.Errorf("unsupported transaction type: %s", rec.Type)
-	}
+		if typeOrder[x.Type] != typeOrder[y.Type] {
+			return typeOrder[x.Type] < typeOrder[y.Type]
+		}
 
-	sort.Slice(b.buys, func(i, j int) bool {
-		return b.buys[i].Timestamp.UnixNano() < b.buys[j].Timestamp.UnixNano()
-	})
+		if x.Account() != y.Account() {
+			return x.Account() < y.Account()
+		}
 
-	sort.Slice(b.sells, func(i, j int) bool {
-		return b.sells[i].Timestamp.UnixNano() < b.sells[j].Timestamp.UnixNano()
+		return x.ID < y.ID
 	})
 
 	return &b, nil
 }
 
-func (b *Book) addCredit(quantity *big.Float, rec *transaction.Tx) (*big.Float, error) {
-	if rec.PayCurrency == transaction.EUR {
-		cr := credit{
-			balance: math.NewFloat().Copy(quantity),
-			buyTx:   rec,
+func (r *Result) warnf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	log.Warnf("accounting: %s", msg)
+	r.Warnings = append(r.Warnings, msg)
+}
+
+func (r *Result) addMatch(lot *Lot, m *Match) {
+	m.Lot = lot
+	lot.Balance = lot.Balance.Sub(m.Quantity)
+	lot.Cost = lot.Cost.Sub(m.Cost)
+	lot.Matches = append(lot.Matches, m)
+	r.Matches = append(r.Matches, m)
+}
+
+// apply books a transaction, transactions must be applied in chronological
+// order.
+func (r *Result) apply(tx *transaction.Tx) error {
+	err := r.book(tx)
+	if err != nil {
+		return err
+	}
+
+	return r.payFees(tx)
+}
+
+func (r *Result) book(tx *transaction.Tx) error {
+	switch tx.Type {
+	case transaction.Buy:
+		return r.exchange(tx, tx.PayCurrency, tx.PriceNoFees(), tx.Currency, tx.Quantity)
+	case transaction.Sell:
+		return r.exchange(tx, tx.Currency, tx.Quantity, tx.PayCurrency, tx.PriceNoFees())
+	case transaction.Income:
+		return r.receive(tx)
+	case transaction.GiftReceived:
+		// the recipient of a gift takes over the acquisition date and
+		// cost of the donor (§23 Abs. 1 S. 3 EStG)
+		if !tx.DonorAcquired.IsZero() {
+			lot := r.acquire(tx, tx.Currency, tx.Quantity, tx.DonorCost)
+			lot.Acquired = tx.DonorAcquired
+			return nil
 		}
-		log.Printf("accounting: recording buy: %+v\n", rec)
 
-		b.records = append(b.records, &cr)
-		return math.NewFloat(), nil
+		r.warnf("acquisition date and cost of the donor of gift %v are unknown, it is accounted at its market value at the receipt, set them with an override", tx)
+		return r.receive(tx)
+	case transaction.GiftSent:
+		return r.gift(tx)
+	case transaction.Lost:
+		return r.lose(tx)
+	case transaction.Deposit, transaction.Withdrawal:
+		// transfers between own wallets do not change the holdings
+	}
+
+	return nil
+}
+
+// receive acquires the received quantity of tx at its market value.
+func (r *Result) receive(tx *transaction.Tx) error {
+	if !tx.PayCurrency.IsFiat() {
+		r.warnf("value of %v is not in €, it is accounted with a cost basis of 0€", tx)
+		r.acquire(tx, tx.Currency, tx.Quantity, math.Decimal{})
+		return nil
 	}
 
-	credit, err := b.findCredit(rec.PayCurrency, rec.Timestamp)
+	value, err := r.euroValue(tx, tx.PayCurrency, tx.PriceNoFees())
 	if err != nil {
-		return nil, fmt.Errorf("could not find credit for buying %v", rec)
+		return err
 	}
 
-	if credit.balance.Cmp(rec.PriceNoFees()) >= 0 {
-		credit.balance.Sub(credit.balance, rec.PriceNoFees())
+	r.acquire(tx, tx.Currency, tx.Quantity, value)
 
-		sellRec := sell{
-			tx:                     rec,
-			holdTime:               rec.Timestamp.Sub(credit.buyTx.Timestamp),
-			quantity:               math.NewFloat().Set(rec.PriceNoFees()),
-			profit:                 math.NewFloat(),
-			paidWithCryptocurrency: true,
-		}
+	return nil
+}
+
+// remove removes quantity of currency from the lots without realizing a
+// profit and returns the created matches.
+func (r *Result) remove(tx *transaction.Tx, currency transaction.Currency, quantity math.Decimal) ([]*Match, error) {
+	var zero math.Decimal
+
+	matches := len(r.Matches)
+
+	_, err := r.dispose(tx, currency, quantity, &zero)
+	if err != nil {
+		return nil, err
+	}
 
-		credit.sells = append(credit.sells, &sellRec)
-		log.Printf("accounting: recording trade: %s\n", sellRec.String())
+	for _, m := range r.Matches[matches:] {
+		m.Proceeds = m.Cost
+		m.Profit = math.Decimal{}
+	}
+
+	return r.Matches[matches:], nil
+}
+
+// gift removes the quantity of tx from the lots without realizing a
+// profit.
+func (r *Result) gift(tx *transaction.Tx) error {
+	matches, err := r.remove(tx, tx.Currency, tx.Quantity)
+	if err != nil {
+		return err
+	}
 
-		return math.NewFloat(), nil
+	for _, m := range matches {
+		m.Gifted = true
 	}
 
-	remainingInPrice := math.NewFloat().Sub(rec.PriceNoFees(), credit.balance)
-	remaining := math.NewFloat().Quo(remainingInPrice, rec.SpotPrice)
+	return nil
+}
 
-	sellRec := sell{
-		tx:                     rec,
-		holdTime:               rec.Timestamp.Sub(credit.buyTx.Timestamp),
-		quantity:               math.NewFloat().Set(credit.balance),
-		profit:                 math.NewFloat(),
-		paidWithCryptocurrency: true,
+// lose removes the lost quantity of tx from the lots without realizing a
+// profit.
+func (r *Result) lose(tx *transaction.Tx) error {
+	matches, err := r.remove(tx, tx.Currency, tx.Quantity)
+	if err != nil {
+		return err
 	}
 
-	credit.balance.Sub(credit.balance, sellRec.quantity)
-	fmt.Println(credit.balance)
-	credit.sells = append(credit.sells, &sellRec)
+	for _, m := range matches {
+		m.Lost = true
+	}
 
-	return b.addCredit(remaining, rec)
+	return nil
 }
 
-func (b *Book) addCredits(recs []*transaction.Tx) error {
-	for _, rec := range b.buys {
-		_, err := b.addCredit(rec.Quantity, rec)
+// payFees removes fees that were paid in a cryptocurrency from the lots.
+// Fees in fiat currencies are advertising costs of the transaction.
+func (r *Result) payFees(tx *transaction.Tx) error {
+	feeCurrency := tx.FeesIn()
+	if feeCurrency == transaction.CurrencyUndef || feeCurrency.IsFiat() {
+		return nil
+	}
+
+	if tx.Fees.Sign() <= 0 {
+		return nil
+	}
+
+	matches, err := r.remove(tx, feeCurrency, tx.Fees)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range matches {
+		m.Fee = true
+	}
+
+	return nil
+}
+
+// exchange books the exchange of fromQuantity of the currency from for
+// toQuantity of the currency to.
+func (r *Result) exchange(tx *transaction.Tx, from transaction.Currency, fromQuantity math.Decimal, to transaction.Currency, toQuantity math.Decimal) error {
+	var value *math.Decimal // in €, nil if unknown
+
+	switch {
+	case from.IsFiat():
+		v, err := r.euroValue(tx, from, fromQuantity)
+		if err != nil {
+			return err
+		}
+		value = &v
+	case to.IsFiat():
+		v, err := r.euroValue(tx, to, toQuantity)
 		if err != nil {
 			return err
 		}
+		value = &v
+	default:
+		value = r.tradeValue(tx, from, fromQuantity, to, toQuantity)
+	}
+
+	if !from.IsFiat() {
+		matches := len(r.Matches)
+
+		cost, err := r.dispose(tx, from, fromQuantity, value)
+		if err != nil {
+			return err
+		}
+
+		if !to.IsFiat() {
+			for _, m := range r.Matches[matches:] {
+				m.PaidWithCryptocurrency = true
+				log.Debugf("accounting: recording trade: %s", m.String())
+			}
+		}
+
+		if value == nil {
+			value = &cost
+		}
+	}
+
+	if !to.IsFiat() {
+		r.acquire(tx, to, toQuantity, *value)
 	}
 
 	return nil
 }
 
-func (b *Book) sell(quantity *big.Float, tx *transaction.Tx) (*big.Float, error) {
-	var sellRec sell
-	var remaining = math.NewFloat()
+// tradeValue returns the € value of a trade between two cryptocurrencies.
+// It is the value of tx or, if it is not set, the value of the exchanged
+// quantities at the prices of the price source. nil is returned if it is
+// unknown.
+func (r *Result) tradeValue(tx *transaction.Tx, from transaction.Currency, fromQuantity math.Decimal, to transaction.Currency, toQuantity math.Decimal) *math.Decimal {
+	if !tx.Value.IsZero() {
+		v := tx.Value
+		return &v
+	}
+
+	if r.opts.Prices != nil {
+		if price, exist := r.opts.Prices.At(from, tx.Timestamp); exist {
+			v := fromQuantity.Mul(price)
+			return &v
+		}
 
-	sellRec.tx = tx
+		if price, exist := r.opts.Prices.At(to, tx.Timestamp); exist {
+			v := toQuantity.Mul(price)
+			return &v
+		}
+	}
 
-	creditRec, err := b.findCredit(tx.Currency, tx.Timestamp)
-	if err != nil {
-		log.Printf("accounting: WARN: could not find buy record for %v: %s, assuming 100%% earning", tx, err)
-		sellRec.holdTime = time.Duration(0)
-		sellRec.quantity = math.NewFloat().Set(quantity)
-		sellRec.profit = tx.PriceNoFees()
-		return math.NewFloat(), nil
+	r.warnf("€ value of %v is unknown, the cost basis is transferred without realizing a profit, set its value with an override or pass a price source for %s or %s",
+		tx, from, to)
+
+	return nil
+}
+
+// euroValue converts amount of the fiat currency to €, at the price of the
+// time of tx.
+func (r *Result) euroValue(tx *transaction.Tx, currency transaction.Currency, amount math.Decimal) (math.Decimal, error) {
+	if currency == transaction.EUR {
+		return amount, nil
 	}
 
-	sellRec.holdTime = tx.Timestamp.Sub(creditRec.buyTx.Timestamp)
+	var price math.Decimal
+	var exist bool
+
+	if r.opts.Prices != nil {
+		price, exist = r.opts.Prices.At(currency, tx.Timestamp)
+	}
 
-	// transaction is smaller or same then balance, we can sub everything and end it
-	if creditRec.balance.Cmp(quantity) >= 0 {
-		sellRec.quantity = math.NewFloat().Set(quantity)
-		sellRec.profit = calcProfit(sellRec.quantity, creditRec.buyTx, tx)
+	if !exist {
+		return math.Decimal{}, fmt.Errorf("no € price of %s at %s is known, it is required for %v, pass a price source",
+			currency, tx.Timestamp.Format(time.RFC3339), tx)
+	}
 
-		creditRec.balance.Sub(creditRec.balance, sellRec.quantity)
-		creditRec.sells = append(creditRec.sells, &sellRec)
+	return amount.Mul(price), nil
+}
 
-		return remaining, nil
+// acquire adds a lot of quantity of currency, that was bought for value €.
+func (r *Result) acquire(tx *transaction.Tx, currency transaction.Currency, quantity, value math.Decimal) *Lot {
+	lot := Lot{
+		Currency: currency,
+		Quantity: quantity,
+		Balance:  quantity,
+		Cost:     value,
+		BuyTx:    tx,
+		Acquired: tx.Timestamp,
 	}
+	r.takeDust(&lot)
 
-	// transaction is bigger then balance
-	remaining.Sub(quantity, creditRec.balance)
-	sellRec.quantity = math.NewFloat().Set(creditRec.balance)
-	sellRec.profit = calcProfit(sellRec.quantity, creditRec.buyTx, tx)
+	log.Debugf("accounting: recording buy: %s %s for %s€", quantity, currency, value)
 
-	creditRec.balance.Sub(creditRec.balance, sellRec.quantity)
-	creditRec.sells = append(creditRec.sells, &sellRec)
+	r.Lots = append(r.Lots, &lot)
 
-	return b.sell(remaining, tx)
+	return &lot
 }
 
-// returns the first buy record (by timestamp) with the same currency and
-// balance>0
-func (b *Book) findCredit(currency transaction.Currency, from time.Time) (*credit, error) {
-	var zero = math.NewFloat()
+// dispose sells quantity of currency from its lots in FIFO order and returns
+// the cost basis of the sold quantity.
+// proceeds is the € value of the sold quantity, if it is nil the proceeds
+// equal the cost basis.
+func (r *Result) dispose(tx *transaction.Tx, currency transaction.Currency, quantity math.Decimal, proceeds *math.Decimal) (math.Decimal, error) {
+	var cost, remainingProceeds math.Decimal
+	remaining := quantity
 
-	for _, brec := range b.records {
-		if brec.balance.Cmp(zero) == 0 {
-			continue
+	if proceeds != nil {
+		remainingProceeds = *proceeds
+	}
+
+	for remaining.Sign() > 0 {
+		lot, err := r.findCredit(currency)
+		if err != nil && r.isDust(currency, remaining) {
+			r.warnf("ignored dust of %s %s of %v, no buy record exists", remaining, currency, tx)
+			break
+		}
+		if err != nil {
+			lot, err = r.unmatchedLot(tx, currency, remaining)
+			if err != nil {
+				return math.Decimal{}, err
+			}
 		}
 
-		if brec.buyTx.Currency != currency {
-			continue
+		m := Match{
+			Tx:       tx,
+			HoldTime: tx.Timestamp.Sub(lot.Acquired),
+			Quantity: remaining.Min(lot.Balance),
 		}
 
-		if brec.buyTx.Timestamp.After(from) {
-			continue
+		m.Cost = share(lot.Cost, m.Quantity, lot.Balance)
+		if proceeds == nil {
+			m.Proceeds = m.Cost
+		} else {
+			m.Proceeds = share(remainingProceeds, m.Quantity, remaining)
+			remainingProceeds = remainingProceeds.Sub(m.Proceeds)
 		}
+		m.Profit = m.Proceeds.Sub(m.Cost)
 
-		return brec, nil
+		r.addMatch(lot, &m)
+		r.handleDust(lot)
+		cost = cost.Add(m.Cost)
+		remaining = remaining.Sub(m.Quantity)
 	}
 
-	return nil, errors.New("does not exist")
+	return cost, nil
 }
 
-func calcProfit(amount *big.Float, buy *transaction.Tx, sell *transaction.Tx) *big.Float {
-	var res big.Float
+// share returns the part of amount that belongs to quantity of total.
+// If quantity equals total, amount is returned unchanged, that way the
+// shares of an amount always sum up exactly to it.
+func share(amount, quantity, total math.Decimal) math.Decimal {
+	if quantity.Cmp(total) == 0 {
+		return amount
+	}
+
+	return amount.Mul(quantity).Quo(total, math.DivScale, math.RoundHalfEven)
+}
+
+// returns the first lot (by timestamp) with the same currency and
+// balance>0
+func (r *Result) findCredit(currency transaction.Currency) (*Lot, error) {
+	for _, lot := range r.Lots {
+		if lot.Balance.Sign() <= 0 {
+			continue
+		}
+
+		if lot.Currency != currency {
+			continue
+		}
 
-	buyPrice := (&big.Float{}).Mul(amount, buy.SpotPrice)
-	sellPrice := (&big.Float{}).Mul(amount, sell.SpotPrice)
+		return lot, nil
+	}
 
-	return res.Sub(sellPrice, buyPrice)
+	return nil, errors.New("does not exist")
 }
 
-func (b *Book) Calculate() error {
-	err := b.addCredits(b.buys)
+// Calculate replays the transactions of the book in chronological order and
+// returns the result. The book is not modified.
+func (b *Book) Calculate(opts Options) (*Result, error) {
+	res := Result{opts: opts}
+
+	err := res.addOpeningBalances(opts.OpeningBalances, b.txs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, tx := range b.sells {
-		_, err := b.sell(tx.Quantity, tx)
+	for _, tx := range b.txs {
+		err := res.apply(tx)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		res.last = tx.Timestamp
 	}
 
-	return nil
+	res.TaxRecords = res.taxRecords()
+
+	return &res, nil
 }
 
-func (b *Book) String() string {
+func (r *Result) String() string {
 	var buf bytes.Buffer
 	tw := tabwriter.NewWriter(&buf, 0, 4, 4, ' ', 0)
 
-	tw.Write([]byte("Balance\tType\tTimestamp\tExchange\tQuantity\tSpot Price\tExchange TX ID\tTX Fees\tProfit\tHold Time in days\tTaxable\n"))
-	var result string
-	for _, rec := range b.records {
-		result += fmt.Sprintf("%s\n", rec)
-		tw.Write([]byte(fmt.Sprintf("%f %s\tBUY\t%s\t%s\t%f %s\t%f %s\t%s\t%f %s\t-\t-\t-\n",
-			rec.balance, rec.buyTx.Currency,
-			rec.buyTx.Timestamp.Format(time.RFC822Z),
-			rec.buyTx.Exchange,
-			rec.buyTx.Quantity, rec.buyTx.Currency,
-			rec.buyTx.SpotPrice, rec.buyTx.PayCurrency,
-			rec.buyTx.ID,
-			rec.buyTx.Fees, rec.buyTx.PayCurrency)))
-
-		for _, sell := range rec.sells {
+	tw.Write([]byte("Balance\tType\tTimestamp\tExchange\tQuantity\tValue\tExchange TX ID\tTX Fees\tProfit\tHold Time in days\tTaxable\n"))
+	for _, lot := range r.Lots {
+		var value math.Decimal
+		for _, m := range lot.Matches {
+			value = value.Add(m.Cost)
+		}
+		value = value.Add(lot.Cost)
+
+		var buyType = "BUY"
+		if lot.BuyTx.Type == transaction.Income {
+			buyType = "INCOME"
+		} else if lot.BuyTx.Type == transaction.GiftReceived {
+			buyType = "GIFT"
+		}
+
+		tw.Write([]byte(fmt.Sprintf("%s %s\t%s\t%s\t%s\t%s %s\t%.2f€\t%s\t%s %s\t-\t-\t-\n",
+			lot.Balance, lot.Currency,
+			buyType,
+			lot.BuyTx.Timestamp.Format(time.RFC822Z),
+			lot.BuyTx.Account(),
+			lot.Quantity, lot.Currency,
+			value,
+			lot.BuyTx.ID,
+			lot.BuyTx.Fees, lot.BuyTx.FeesIn())))
+
+		for _, m := range lot.Matches {
 			var sellType = "SELL"
-			if sell.paidWithCryptocurrency {
+			if m.PaidWithCryptocurrency {
 				sellType = "TRADE"
+			} else if m.Gifted {
+				sellType = "GIFT"
+			} else if m.Fee {
+				sellType = "FEE"
+			} else if m.Lost {
+				sellType = "LOST"
 			}
 
-			tw.Write([]byte(fmt.Sprintf("-\t%s\t%s\t%s\t%f %s\t%f %s\t%s\t%f %s\t%f %s\t%f\t%v\n",
+			tw.Write([]byte(fmt.Sprintf("-\t%s\t%s\t%s\t%s %s\t%.2f€\t%s\t%s %s\t%.2f€\t%f\t%v\n",
 				sellType,
-				sell.tx.Timestamp.Format(time.RFC822Z),
-				sell.tx.Exchange,
-				sell.quantity, sell.tx.Currency,
-				sell.tx.SpotPrice, sell.tx.PayCurrency,
-				sell.tx.ID,
-				sell.tx.Fees, sell.tx.PayCurrency,
-				sell.profit, sell.tx.PayCurrency,
-				sell.holdTime.Hours()/24,
-				sell.HoldTimeIsLessThenYear(),
+				m.Tx.Timestamp.Format(time.RFC822Z),
+				m.Tx.Account(),
+				m.Quantity, lot.Currency,
+				m.Proceeds,
+				m.Tx.ID,
+				m.Tx.Fees, m.Tx.FeesIn(),
+				m.Profit,
+				m.HoldTime.Hours()/24,
+				m.HoldTimeIsLessThenYear(),
 			)))
 		}
 	}
@@ -302,17 +589,21 @@ func (b *Book) String() string {
 	return buf.String()
 }
 
-func (b *Book) TaxReport(full bool) string {
+// TaxReport returns the tax records of a year, excluding the tax free
+// ones. If year is 0 all tax records are included.
+func (r *Result) TaxReport(year int) string {
 	var buf bytes.Buffer
 	var count int
-	var earnings = math.NewFloat()
-	var loss = math.NewFloat()
+	var earnings math.Decimal
+	var loss math.Decimal
+
+	full := year == 0
 
 	tw := tabwriter.NewWriter(&buf, 0, 4, 4, ' ', 0)
 	tw.Write([]byte("# Tax Year\tHold >=1Year\tCurrency\tBuy Date\tSell Date\tSell Price\t Buy Price\tAdvertisment Costs\n"))
 
-	for _, tr := range b.TaxRecords() {
-		if !full && b.taxYear != tr.TaxYear {
+	for _, tr := range r.TaxRecords {
+		if !full && year != tr.TaxYear {
 			continue
 		}
 
@@ -321,7 +612,7 @@ func (b *Book) TaxReport(full bool) string {
 		}
 
 		count++
-		tw.Write([]byte(fmt.Sprintf("%d\t%v\t%s\t%s\t%s\t%f€\t%f€\t%f€\n",
+		tw.Write([]byte(fmt.Sprintf("%d\t%v\t%s\t%s\t%s\t%.2f€\t%.2f€\t%.2f€\n",
 			tr.TaxYear,
 			tr.HoldLongerThenAYear,
 			tr.Currency,
@@ -332,58 +623,53 @@ func (b *Book) TaxReport(full bool) string {
 			tr.AdvertisingCosts,
 		)))
 
-		profit := math.NewFloat().Sub(tr.SellPrice, tr.BuyPrice)
-		profit = profit.Sub(profit, tr.AdvertisingCosts)
+		profit := tr.SellPrice.Sub(tr.BuyPrice).Sub(tr.AdvertisingCosts)
 
-		if profit.Cmp(new(big.Float)) >= 0 {
-			earnings.Add(earnings, profit)
+		if profit.Sign() >= 0 {
+			earnings = earnings.Add(profit)
 		} else {
-			loss.Add(loss, profit)
+			loss = loss.Add(profit)
 		}
 	}
 
 	tw.Flush()
 	buf.Write([]byte(fmt.Sprintf("---\nCount: %d\n", count)))
-	buf.Write([]byte(fmt.Sprintf("Earning: %f€\n", earnings)))
-	buf.Write([]byte(fmt.Sprintf("Loss: %f€\n", loss)))
+	buf.Write([]byte(fmt.Sprintf("Earning: %.2f€\n", earnings)))
+	buf.Write([]byte(fmt.Sprintf("Loss: %.2f€\n", loss)))
 
 	return buf.String()
 }
 
-func (b *Book) TaxRecords() []*TaxRecord {
-	var result []*TaxRecord
+// euroFees returns the fees of tx in € if they were paid in a fiat
+// currency. Fees in cryptocurrencies are booked as separate matches.
+func (r *Result) euroFees(tx *transaction.Tx) math.Decimal {
+	feeCurrency := tx.FeesIn()
+	if !feeCurrency.IsFiat() || tx.Fees.IsZero() {
+		return math.Decimal{}
+	}
 
-	includedFees := map[string]interface{}{}
+	fees, err := r.euroValue(tx, feeCurrency, tx.Fees)
+	if err != nil {
+		r.warnf("fees are ignored: %s", err)
+		return math.Decimal{}
+	}
 
-	for _, rec := range b.records {
-		for _, sell := range rec.sells {
-			fees := math.NewFloat()
-			if _, exist := includedFees[sell.tx.ID]; !exist {
-				includedFees[sell.tx.ID] = struct{}{}
-				fees = fees.Add(fees, sell.tx.Fees)
-			}
+	return fees
+}
 
-			if _, exist := includedFees[rec.buyTx.ID]; !exist {
-				includedFees[rec.buyTx.ID] = struct{}{}
-				fees = fees.Add(fees, rec.buyTx.Fees)
-			}
+func (r *Result) taxRecords() []*TaxRecord {
+	var result []*TaxRecord
 
-			tr := TaxRecord{
-				Currency:            rec.buyTx.Currency,
-				BuyTs:               rec.buyTx.Timestamp,
-				SellTs:              sell.tx.Timestamp,
-				SellPrice:           math.NewFloat().Mul(sell.quantity, sell.tx.SpotPrice),
-				BuyPrice:            math.NewFloat().Mul(sell.quantity, rec.buyTx.SpotPrice),
-				AdvertisingCosts:    fees,
-				HoldLongerThenAYear: !sell.HoldTimeIsLessThenYear(),
-				TaxYear:             sell.tx.Timestamp.Year(),
-			}
+	includedFees := map[string]interface{}{}
 
-			result = append(result, &tr)
+	for _, m := range r.Matches {
+		tr := r.taxRecord(m, includedFees)
+		if tr != nil {
+			result = append(result, tr)
 		}
 	}
 
-	sort.Slice(result, func(i, j int) bool {
+	sort.SliceStable(result, func(i, j int) bool {
 		if result[i].TaxYear != result[j].TaxYear {
 			return result[i].TaxYear < result[j].TaxYear
 		}
@@ -398,3 +684,47 @@ func (b *Book) TaxRecords() []*TaxRecord {
 
 	return result
 }
+
+// taxRecord returns the tax record of m, nil is returned if m is no
+// private sale. The € fees of the sell and buy transaction are included in
+// the first record of the transaction, includedFees contains the IDs of
+// the transactions whose fees were included.
+func (r *Result) taxRecord(m *Match, includedFees map[string]interface{}) *TaxRecord {
+	// gifts, fees and losses are no private sales
+	if m.Gifted || m.Fee || m.Lost {
+		return nil
+	}
+
+	var fees math.Decimal
+	if _, exist := includedFees[m.Tx.ID]; !exist {
+		includedFees[m.Tx.ID] = struct{}{}
+		fees = fees.Add(r.euroFees(m.Tx))
+	}
+
+	if _, exist := includedFees[m.Lot.BuyTx.ID]; !exist {
+		includedFees[m.Lot.BuyTx.ID] = struct{}{}
+		fees = fees.Add(r.euroFees(m.Lot.BuyTx))
+	}
+
+	return &TaxRecord{
+		Currency:            m.Lot.Currency,
+		BuyTs:               m.Lot.Acquired,
+		SellTs:              m.Tx.Timestamp,
+		SellPrice:           m.Proceeds,
+		BuyPrice:            m.Cost,
+		AdvertisingCosts:    fees,
+		HoldLongerThenAYear: !m.HoldTimeIsLessThenYear(),
+		TaxYear:             m.Tx.Timestamp.Year(),
+	}
+}
+
+// WarningsReport returns the warnings that occurred during the calculation.
+func (r *Result) WarningsReport() string {
+	var buf bytes.Buffer
+
+	for _, w := range r.Warnings {
+		buf.Write([]byte(fmt.Sprintf("WARNING: %s\n", w)))
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,365 @@
+package accounting
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+var t0 = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func day(n int) time.Time {
+	return t0.Add(time.Duration(n) * 24 * time.Hour)
+}
+
+func dec(s string) math.Decimal {
+	return math.MustParseDecimal(s)
+}
+
+// trade returns a buy or sell of quantity of currency for price per unit
+// in payCurrency.
+func trade(id string, ts time.Time, txType transaction.Type, currency transaction.Currency, quantity string, payCurrency transaction.Currency, price string) *transaction.Tx {
+	return &transaction.Tx{
+		ID:          id,
+		Exchange:    "Test",
+		Timestamp:   ts,
+		Type:        txType,
+		PayCurrency: payCurrency,
+		Currency:    currency,
+		Quantity:    dec(quantity),
+		SpotPrice:   dec(price),
+	}
+}
+
+// prices is a PriceSource with a constant price per currency.
+type prices map[transaction.Currency]math.Decimal
+
+func (p prices) At(currency transaction.Currency, _ time.Time) (math.Decimal, bool) {
+	price, exist := p[currency]
+	return price, exist
+}
+
+func calculate(t *testing.T, opts Options, records ...*transaction.Tx) *Result {
+	t.Helper()
+
+	book, err := NewBook(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := book.Calculate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func assertDecimal(t *testing.T, name string, got math.Decimal, want string) {
+	t.Helper()
+
+	if got.Cmp(dec(want)) != 0 {
+		t.Errorf("%s is %s, want %s", name, got, want)
+	}
+}
+
+func assertTaxRecord(t *testing.T, tr *TaxRecord, currency transaction.Currency, buyTs, sellTs time.Time, sellPrice, buyPrice, costs string) {
+	t.Helper()
+
+	if tr.Currency != currency {
+		t.Errorf("tax record currency is %s, want %s", tr.Currency, currency)
+	}
+
+	if !tr.BuyTs.Equal(buyTs) || !tr.SellTs.Equal(sellTs) {
+		t.Errorf("tax record is from %s to %s, want %s to %s", tr.BuyTs, tr.SellTs, buyTs, sellTs)
+	}
+
+	assertDecimal(t, "sell price", tr.SellPrice, sellPrice)
+	assertDecimal(t, "buy price", tr.BuyPrice, buyPrice)
+	assertDecimal(t, "advertising costs", tr.AdvertisingCosts, costs)
+}
+
+func TestSellAndRebuy(t *testing.T) {
+	b1 := trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000")
+	s1 := trade("s1", day(10), transaction.Sell, transaction.BTC, "1", transaction.EUR, "15000")
+	b2 := trade("b2", day(20), transaction.Buy, transaction.BTC, "1", transaction.EUR, "12000")
+	s2 := trade("s2", day(30), transaction.Sell, transaction.BTC, "0.5", transaction.EUR, "20000")
+
+	// the order of the records must not matter
+	res := calculate(t, Options{}, s2, b2, s1, b1)
+
+	if len(res.TaxRecords) != 2 {
+		t.Fatalf("got %d tax records, want 2", len(res.TaxRecords))
+	}
+
+	assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(0), day(10), "15000", "10000", "0")
+	assertTaxRecord(t, res.TaxRecords[1], transaction.BTC, day(20), day(30), "10000", "6000", "0")
+
+	if len(res.Lots) != 2 {
+		t.Fatalf("got %d lots, want 2", len(res.Lots))
+	}
+
+	assertDecimal(t, "balance of first lot", res.Lots[0].Balance, "0")
+	assertDecimal(t, "balance of second lot", res.Lots[1].Balance, "0.5")
+	assertDecimal(t, "cost of second lot", res.Lots[1].Cost, "6000")
+
+	if res.Matches[1].HoldTime != 10*24*time.Hour {
+		t.Errorf("hold time of the second sell is %s, want 240h", res.Matches[1].HoldTime)
+	}
+
+	if len(res.Unmatched) != 0 {
+		t.Errorf("got %d unmatched sells, want 0", len(res.Unmatched))
+	}
+}
+
+func TestBuyPaidWithCryptocurrency(t *testing.T) {
+	records := func(value string) []*transaction.Tx {
+		swap := trade("swap", day(100), transaction.Buy, transaction.ETH, "10", transaction.BTC, "0.05")
+		swap.Value = dec(value)
+
+		return []*transaction.Tx{
+			trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+			swap,
+			trade("s1", day(200), transaction.Sell, transaction.ETH, "10", transaction.EUR, "1000"),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		opts     Options
+		proceeds string
+		warnings int
+	}{
+		{name: "value of the transaction", value: "8000", proceeds: "8000"},
+		{name: "price source", value: "0", opts: Options{Prices: prices{transaction.BTC: dec("20000")}}, proceeds: "10000"},
+		{name: "price of the bought currency", value: "0", opts: Options{Prices: prices{transaction.ETH: dec("900")}}, proceeds: "9000"},
+		{name: "unknown value", value: "0", proceeds: "5000", warnings: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := calculate(t, tt.opts, records(tt.value)...)
+
+			if len(res.Warnings) != tt.warnings {
+				t.Errorf("got warnings %q, want %d", res.Warnings, tt.warnings)
+			}
+
+			if len(res.TaxRecords) != 2 {
+				t.Fatalf("got %d tax records, want 2", len(res.TaxRecords))
+			}
+
+			// the swap is a sale of the paid currency and the
+			// acquisition of the bought one
+			assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(0), day(100), tt.proceeds, "5000", "0")
+			assertTaxRecord(t, res.TaxRecords[1], transaction.ETH, day(100), day(200), "10000", tt.proceeds, "0")
+
+			if !res.Matches[0].PaidWithCryptocurrency {
+				t.Error("match of the swap is not marked as paid with cryptocurrency")
+			}
+
+			assertDecimal(t, "BTC balance", res.Lots[0].Balance, "0.5")
+		})
+	}
+}
+
+func TestCryptocurrencyFees(t *testing.T) {
+	withdrawal := &transaction.Tx{
+		ID:          "w1",
+		Exchange:    "Test",
+		Timestamp:   day(10),
+		Type:        transaction.Withdrawal,
+		Currency:    transaction.BTC,
+		Quantity:    dec("0.5"),
+		Fees:        dec("0.001"),
+		FeeCurrency: transaction.BTC,
+	}
+
+	// the fees are in the pay currency BTC
+	swap := trade("swap", day(20), transaction.Buy, transaction.ETH, "1", transaction.BTC, "0.1")
+	swap.Fees = dec("0.0005")
+	swap.Value = dec("2000")
+
+	res := calculate(t, Options{},
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		withdrawal,
+		swap,
+	)
+
+	assertDecimal(t, "BTC balance", res.Lots[0].Balance, "0.8985")
+
+	var fees int
+	for _, m := range res.Matches {
+		if !m.Fee {
+			continue
+		}
+		fees++
+
+		if m.Profit.Sign() != 0 {
+			t.Errorf("fee match %s realized a profit", m)
+		}
+	}
+
+	if fees != 2 {
+		t.Errorf("got %d fee matches, want 2", fees)
+	}
+
+	if len(res.TaxRecords) != 1 {
+		t.Fatalf("got %d tax records, want 1", len(res.TaxRecords))
+	}
+
+	assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(0), day(20), "2000", "1000", "0")
+}
+
+func TestFiatFees(t *testing.T) {
+	b1 := trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000")
+	b1.Fees = dec("10")
+
+	s1 := trade("s1", day(10), transaction.Sell, transaction.BTC, "1", transaction.USD, "12000")
+	s1.Fees = dec("12")
+
+	res := calculate(t, Options{Prices: prices{transaction.USD: dec("0.5")}}, b1, s1)
+
+	if len(res.TaxRecords) != 1 {
+		t.Fatalf("got %d tax records, want 1", len(res.TaxRecords))
+	}
+
+	assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(0), day(10), "6000", "10000", "16")
+
+	if len(res.Lots) != 1 {
+		t.Errorf("got %d lots, want 1, fiat fees must not be removed from the holdings", len(res.Lots))
+	}
+}
+
+func TestSameTimestampOrder(t *testing.T) {
+	buy := trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000")
+	buy.Exchange = "Kraken"
+
+	income := trade("i1", day(0), transaction.Income, transaction.BTC, "1", transaction.EUR, "20000")
+	income.Exchange = "Coinbase"
+
+	sell := trade("s1", day(10), transaction.Sell, transaction.BTC, "1", transaction.EUR, "30000")
+
+	a := calculate(t, Options{}, buy, income, sell)
+	b := calculate(t, Options{}, income, buy, sell)
+
+	if a.String() != b.String() {
+		t.Errorf("the results depend on the order of the records:\n%s\n%s", a, b)
+	}
+
+	// acquisitions with the same timestamp are ordered by account
+	assertTaxRecord(t, a.TaxRecords[0], transaction.BTC, day(0), day(10), "30000", "20000", "0")
+}
+
+func snapshot(records []*transaction.Tx) []string {
+	var res []string
+	for _, tx := range records {
+		res = append(res, fmt.Sprintf("%+v", *tx))
+	}
+
+	return res
+}
+
+func TestCalculateIsRepeatable(t *testing.T) {
+	swap := trade("swap", day(15), transaction.Buy, transaction.ETH, "10", transaction.BTC, "0.05")
+	swap.Value = dec("8000")
+	swap.Fees = dec("0.001")
+
+	records := []*transaction.Tx{
+		trade("s2", day(30), transaction.Sell, transaction.BTC, "0.2", transaction.EUR, "20000"),
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		trade("s1", day(10), transaction.Sell, transaction.BTC, "0.25", transaction.EUR, "15000"),
+		swap,
+		trade("b2", day(20), transaction.Buy, transaction.BTC, "1", transaction.EUR, "12000"),
+		trade("s3", day(40), transaction.Sell, transaction.ETH, "4", transaction.EUR, "900"),
+	}
+
+	before := snapshot(records)
+	order := append([]*transaction.Tx(nil), records...)
+
+	book, err := NewBook(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs := append([]*transaction.Tx(nil), book.txs...)
+
+	first, err := book.Calculate(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstStr := first.String()
+	firstReport := first.TaxReport(0)
+
+	second, err := book.Calculate(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.String() != firstStr || second.TaxReport(0) != firstReport {
+		t.Errorf("results of repeated calculations differ:\n%s\n%s", firstStr, second)
+	}
+
+	if !reflect.DeepEqual(first.TaxRecords, second.TaxRecords) {
+		t.Error("tax records of repeated calculations differ")
+	}
+
+	if first.String() != firstStr {
+		t.Error("the first result was modified by the second calculation")
+	}
+
+	if !reflect.DeepEqual(snapshot(records), before) {
+		t.Errorf("the transactions were modified:\n%q\n%q", before, snapshot(records))
+	}
+
+	if !reflect.DeepEqual(records, order) {
+		t.Error("the order of the passed records was modified")
+	}
+
+	if !reflect.DeepEqual(book.txs, txs) {
+		t.Error("the transactions of the book were modified")
+	}
+
+	if len(first.Lots) != 3 {
+		t.Errorf("got %d lots, want 3", len(first.Lots))
+	}
+}
+
+func TestGiftReceived(t *testing.T) {
+	gift := func() *transaction.Tx {
+		return trade("g1", day(0), transaction.GiftReceived, transaction.BTC, "1", transaction.EUR, "20000")
+	}
+	sell := trade("s1", day(10), transaction.Sell, transaction.BTC, "1", transaction.EUR, "30000")
+
+	t.Run("donor acquisition", func(t *testing.T) {
+		g := gift()
+		g.DonorAcquired = day(-400)
+		g.DonorCost = dec("1000")
+
+		res := calculate(t, Options{}, g, sell)
+
+		if len(res.Warnings) != 0 {
+			t.Errorf("got warnings %q, want none", res.Warnings)
+		}
+
+		// the gift takes over the acquisition date and cost of the donor
+		assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(-400), day(10), "30000", "1000", "0")
+
+		if !res.TaxRecords[0].HoldLongerThenAYear {
+			t.Error("the holding period of the donor was not taken over")
+		}
+	})
+
+	t.Run("unknown donor acquisition", func(t *testing.T) {
+		res := calculate(t, Options{}, gift(), sell)
+
+		if len(res.Warnings) != 1 {
+			t.Errorf("got warnings %q, want 1", res.Warnings)
+		}
+
+		assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(0), day(10), "30000", "20000", "0")
+	})
+}
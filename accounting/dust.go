@@ -0,0 +1,123 @@
+package accounting
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// DustPolicy defines how remainders of lots that are smaller then the dust
+// threshold are handled.
+type DustPolicy int
+
+const (
+	// DustWriteOff removes dust from the lot, its cost basis is lost
+	DustWriteOff DustPolicy = iota
+	// DustCarry moves dust and its cost basis to the next lot of the
+	// same currency
+	DustCarry
+)
+
+var strToDustPolicy = map[string]DustPolicy{
+	"write-off": DustWriteOff,
+	"carry":     DustCarry,
+}
+
+var dustPolicyToStr = map[DustPolicy]string{
+	DustWriteOff: "write-off",
+	DustCarry:    "carry",
+}
+
+var ErrUndefinedDustPolicy = errors.New("unsupported dust policy")
+
+func NewDustPolicy(policy string) (DustPolicy, error) {
+	res, ok := strToDustPolicy[strings.ToLower(policy)]
+	if !ok {
+		return 0, ErrUndefinedDustPolicy
+	}
+
+	return res, nil
+}
+
+func (p DustPolicy) String() string {
+	res, ok := dustPolicyToStr[p]
+	if !ok {
+		return "undefined"
+	}
+
+	return res
+}
+
+// dust holds carried dust that has not been moved to a lot yet
+type dust struct {
+	quantity math.Decimal
+	cost     math.Decimal
+}
+
+// isDust returns true if quantity is positive and not bigger then the dust
+// threshold of currency.
+func (r *Result) isDust(currency transaction.Currency, quantity math.Decimal) bool {
+	return quantity.Sign() > 0 && quantity.Cmp(r.opts.dustThreshold(currency)) <= 0
+}
+
+// handleDust applies the dust policy to the remaining balance of lot, if it
+// is dust.
+func (r *Result) handleDust(lot *Lot) {
+	if !r.isDust(lot.Currency, lot.Balance) {
+		return
+	}
+
+	balance, cost := lot.Balance, lot.Cost
+	lot.Balance, lot.Cost = math.Decimal{}, math.Decimal{}
+
+	if r.opts.DustPolicy == DustWriteOff {
+		r.warnf("wrote off dust of %s %s with a cost basis of %.2f€ from lot bought %s (%s %s)",
+			balance, lot.Currency, cost,
+			lot.BuyTx.Timestamp.Format(TimeFormat), lot.BuyTx.Account(), lot.BuyTx.ID)
+		return
+	}
+
+	for _, next := range r.Lots {
+		if next == lot || next.Currency != lot.Currency || next.Balance.Sign() <= 0 {
+			continue
+		}
+
+		next.Balance = next.Balance.Add(balance)
+		next.Cost = next.Cost.Add(cost)
+		r.warnf("carried dust of %s %s from lot bought %s (%s %s) to lot bought %s (%s %s)",
+			balance, lot.Currency,
+			lot.BuyTx.Timestamp.Format(TimeFormat), lot.BuyTx.Account(), lot.BuyTx.ID,
+			next.BuyTx.Timestamp.Format(TimeFormat), next.BuyTx.Account(), next.BuyTx.ID)
+		return
+	}
+
+	if r.dust == nil {
+		r.dust = map[transaction.Currency]*dust{}
+	}
+
+	d, exist := r.dust[lot.Currency]
+	if !exist {
+		d = &dust{}
+		r.dust[lot.Currency] = d
+	}
+
+	d.quantity = d.quantity.Add(balance)
+	d.cost = d.cost.Add(cost)
+	r.warnf("carried dust of %s %s from lot bought %s (%s %s) to the next acquisition",
+		balance, lot.Currency,
+		lot.BuyTx.Timestamp.Format(TimeFormat), lot.BuyTx.Account(), lot.BuyTx.ID)
+}
+
+// takeDust adds carried dust of the currency of lot to it.
+func (r *Result) takeDust(lot *Lot) {
+	d, exist := r.dust[lot.Currency]
+	if !exist {
+		return
+	}
+
+	lot.Balance = lot.Balance.Add(d.quantity)
+	lot.Cost = lot.Cost.Add(d.cost)
+	delete(r.dust, lot.Currency)
+}
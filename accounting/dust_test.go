@@ -0,0 +1,103 @@
+package accounting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestDust(t *testing.T) {
+	buy1 := trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000")
+	buy1.Wallet = "hot"
+	buy2 := trade("b2", day(5), transaction.Buy, transaction.BTC, "1", transaction.EUR, "20000")
+	// leaves 0.0000001 BTC with a cost basis of 0.001€ in the first lot
+	sell := trade("s1", day(10), transaction.Sell, transaction.BTC, "0.9999999", transaction.EUR, "30000")
+	laterBuy2 := trade("b2", day(20), transaction.Buy, transaction.BTC, "1", transaction.EUR, "20000")
+
+	tests := []struct {
+		name    string
+		policy  DustPolicy
+		records []*transaction.Tx
+		balance string
+		cost    string
+		warning string
+	}{
+		{
+			name:    "write-off",
+			policy:  DustWriteOff,
+			records: []*transaction.Tx{buy1, buy2, sell},
+			balance: "1",
+			cost:    "20000",
+			warning: "wrote off dust of 0.0000001 BTC with a cost basis of 0.00€ from lot bought 01.01.2021 (Test/hot b1)",
+		},
+		{
+			name:    "carry to open lot",
+			policy:  DustCarry,
+			records: []*transaction.Tx{buy1, buy2, sell},
+			balance: "1.0000001",
+			cost:    "20000.001",
+			warning: "carried dust of 0.0000001 BTC from lot bought 01.01.2021 (Test/hot b1) to lot bought 06.01.2021 (Test b2)",
+		},
+		{
+			name:    "carry to next acquisition",
+			policy:  DustCarry,
+			records: []*transaction.Tx{buy1, sell, laterBuy2},
+			balance: "1.0000001",
+			cost:    "20000.001",
+			warning: "carried dust of 0.0000001 BTC from lot bought 01.01.2021 (Test/hot b1) to the next acquisition",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := calculate(t, Options{DustThreshold: dec("0.000001"), DustPolicy: tt.policy}, tt.records...)
+
+			if len(res.Lots) != 2 {
+				t.Fatalf("got %d lots, want 2", len(res.Lots))
+			}
+
+			assertDecimal(t, "balance of the dust lot", res.Lots[0].Balance, "0")
+			assertDecimal(t, "balance of the second lot", res.Lots[1].Balance, tt.balance)
+			assertDecimal(t, "cost of the second lot", res.Lots[1].Cost, tt.cost)
+
+			if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], tt.warning) {
+				t.Errorf("got warnings %q, want one containing %q", res.Warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestDustThresholdPerCurrency(t *testing.T) {
+	opts := Options{
+		DustThreshold:  dec("0.000001"),
+		DustThresholds: map[transaction.Currency]math.Decimal{transaction.BTC: dec("0")},
+	}
+
+	res := calculate(t, opts,
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		trade("s1", day(10), transaction.Sell, transaction.BTC, "0.9999999", transaction.EUR, "30000"),
+	)
+
+	assertDecimal(t, "balance", res.Lots[0].Balance, "0.0000001")
+
+	if len(res.Warnings) != 0 {
+		t.Errorf("got warnings %q, want none", res.Warnings)
+	}
+}
+
+func TestDustWithoutBuyRecord(t *testing.T) {
+	res := calculate(t, Options{DustThreshold: dec("0.000001")},
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		trade("s1", day(10), transaction.Sell, transaction.BTC, "1.0000001", transaction.EUR, "30000"),
+	)
+
+	if len(res.Unmatched) != 0 {
+		t.Errorf("got %d unmatched sells, want none", len(res.Unmatched))
+	}
+
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "ignored dust of 0.0000001 BTC") {
+		t.Errorf("got warnings %q, want one about ignored dust", res.Warnings)
+	}
+}
@@ -0,0 +1,55 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteTaxRecordsCSV writes the tax records of year as CSV to w. If year is
+// 0 the records of all years are written.
+// Amounts are in € and rounded to cents.
+func (r *Result) WriteTaxRecordsCSV(w io.Writer, year int) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write([]string{
+		"tax_year",
+		"currency",
+		"buy_date",
+		"sell_date",
+		"hold_longer_than_a_year",
+		"sell_price",
+		"buy_price",
+		"advertising_costs",
+		"profit",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, tr := range r.TaxRecords {
+		if year != 0 && tr.TaxYear != year {
+			continue
+		}
+
+		err := cw.Write([]string{
+			strconv.Itoa(tr.TaxYear),
+			tr.Currency.String(),
+			tr.BuyTs.Format(time.RFC3339),
+			tr.SellTs.Format(time.RFC3339),
+			strconv.FormatBool(tr.HoldLongerThenAYear),
+			tr.SellPrice.StringFixed(2),
+			tr.BuyPrice.StringFixed(2),
+			tr.AdvertisingCosts.StringFixed(2),
+			tr.SellPrice.Sub(tr.BuyPrice).Sub(tr.AdvertisingCosts).StringFixed(2),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,131 @@
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// TaxFreeLot is the remaining balance of a buy that is still held.
+type TaxFreeLot struct {
+	Currency  transaction.Currency
+	Quantity  math.Decimal
+	BuyTs     time.Time
+	TaxFreeTs time.Time // first point in time when the lot can be sold tax free
+}
+
+// TaxFreeForecast lists when the holdings of a currency become tax free.
+type TaxFreeForecast struct {
+	Currency transaction.Currency
+	// Quantity that can be sold at the reference time without
+	// realizing a taxable profit, when the lots are sold in FIFO order
+	TaxFreeQuantity math.Decimal
+	// Open lots that are not tax free yet, ordered by TaxFreeTs
+	Upcoming []*TaxFreeLot
+}
+
+// TaxFreeTs returns the first point in time when a quantity bought at buyTs
+// can be sold tax free. The holding period is a calendar year, it must be
+// exceeded and is a day longer if it contains a 29th February.
+func TaxFreeTs(buyTs time.Time) time.Time {
+	return buyTs.AddDate(1, 0, 0).Add(time.Nanosecond)
+}
+
+// TaxFreeForecast returns the tax free forecast per currency for the open
+// lots at the point in time at.
+func (r *Result) TaxFreeForecast(at time.Time) []*TaxFreeForecast {
+	var result []*TaxFreeForecast
+	byCurrency := map[transaction.Currency]*TaxFreeForecast{}
+	// fifoBlocked is true for a currency when an older lot that is not
+	// tax free exists, it would be sold first
+	fifoBlocked := map[transaction.Currency]bool{}
+
+	for _, lot := range r.Lots {
+		if lot.Balance.Sign() <= 0 {
+			continue
+		}
+
+		if lot.BuyTx.Timestamp.After(at) {
+			continue
+		}
+
+		cur := lot.Currency
+		fc, exist := byCurrency[cur]
+		if !exist {
+			fc = &TaxFreeForecast{Currency: cur}
+			byCurrency[cur] = fc
+			result = append(result, fc)
+		}
+
+		taxFreeTs := TaxFreeTs(lot.Acquired)
+		if !taxFreeTs.After(at) {
+			if !fifoBlocked[cur] {
+				fc.TaxFreeQuantity = fc.TaxFreeQuantity.Add(lot.Balance)
+			}
+			continue
+		}
+
+		fifoBlocked[cur] = true
+		fc.Upcoming = append(fc.Upcoming, &TaxFreeLot{
+			Currency:  cur,
+			Quantity:  lot.Balance,
+			BuyTs:     lot.Acquired,
+			TaxFreeTs: taxFreeTs,
+		})
+	}
+
+	for _, fc := range result {
+		sort.SliceStable(fc.Upcoming, func(i, j int) bool {
+			return fc.Upcoming[i].TaxFreeTs.Before(fc.Upcoming[j].TaxFreeTs)
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Currency < result[j].Currency
+	})
+
+	return result
+}
+
+// TaxFreeReport returns a human readable report of TaxFreeForecast.
+func (r *Result) TaxFreeReport(at time.Time) string {
+	var buf bytes.Buffer
+
+	tw := tabwriter.NewWriter(&buf, 0, 4, 4, ' ', 0)
+	tw.Write([]byte("# Currency\tTax Free Now\tBuy Date\tTax Free Date\tQuantity\tDays Left\n"))
+
+	for _, fc := range r.TaxFreeForecast(at) {
+		tw.Write([]byte(fmt.Sprintf("%s\t%s\t-\t-\t-\t-\n",
+			fc.Currency, fc.TaxFreeQuantity)))
+
+		for _, tl := range fc.Upcoming {
+			tw.Write([]byte(fmt.Sprintf("%s\t-\t%s\t%s\t%s\t%d\n",
+				tl.Currency,
+				tl.BuyTs.Format(TimeFormat),
+				tl.TaxFreeTs.Format(TimeFormat),
+				tl.Quantity,
+				daysUntil(at, tl.TaxFreeTs),
+			)))
+		}
+	}
+
+	tw.Flush()
+
+	return buf.String()
+}
+
+// daysUntil returns the number of started days from from until to.
+func daysUntil(from, to time.Time) int {
+	d := to.Sub(from)
+	days := int(d / (24 * time.Hour))
+	if d%(24*time.Hour) != 0 {
+		days++
+	}
+
+	return days
+}
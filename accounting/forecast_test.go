@@ -0,0 +1,91 @@
+package accounting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestTaxFreeTs(t *testing.T) {
+	tests := []struct {
+		buyTs time.Time
+		want  time.Time
+	}{
+		{
+			buyTs: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2023, 6, 1, 12, 0, 0, 1, time.UTC),
+		},
+		// the year after the buy contains the 29th February
+		{
+			buyTs: time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 3, 1, 12, 0, 0, 1, time.UTC),
+		},
+		{
+			buyTs: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, 3, 1, 12, 0, 0, 1, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		if got := TaxFreeTs(tt.buyTs); !got.Equal(tt.want) {
+			t.Errorf("TaxFreeTs(%s) = %s, want %s", tt.buyTs, got, tt.want)
+		}
+	}
+}
+
+func TestTaxFreeForecastLeapYear(t *testing.T) {
+	buyTs := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	res := calculate(t, Options{},
+		trade("b1", buyTs, transaction.Buy, transaction.BTC, "1", transaction.EUR, "20000"),
+	)
+
+	// 365 days after the buy, the holding period ends a day later
+	at := time.Date(2024, 2, 29, 13, 0, 0, 0, time.UTC)
+
+	fc := res.TaxFreeForecast(at)
+	if len(fc) != 1 {
+		t.Fatalf("got %d forecasts, want 1", len(fc))
+	}
+
+	assertDecimal(t, "tax free quantity", fc[0].TaxFreeQuantity, "0")
+
+	if len(fc[0].Upcoming) != 1 {
+		t.Fatalf("got %d upcoming lots, want 1", len(fc[0].Upcoming))
+	}
+
+	if want := TaxFreeTs(buyTs); !fc[0].Upcoming[0].TaxFreeTs.Equal(want) {
+		t.Errorf("lot becomes tax free at %s, want %s", fc[0].Upcoming[0].TaxFreeTs, want)
+	}
+
+	fc = res.TaxFreeForecast(time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC))
+	assertDecimal(t, "tax free quantity", fc[0].TaxFreeQuantity, "1")
+
+	if len(fc[0].Upcoming) != 0 {
+		t.Errorf("got %d upcoming lots, want 0", len(fc[0].Upcoming))
+	}
+}
+
+func TestHoldTimeLeapYear(t *testing.T) {
+	tests := []struct {
+		name    string
+		sellTs  time.Time
+		taxFree bool
+	}{
+		{name: "365 days", sellTs: time.Date(2024, 2, 29, 13, 0, 0, 0, time.UTC)},
+		{name: "one calendar year", sellTs: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "more than a year", sellTs: time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC), taxFree: true},
+	}
+
+	for _, tt := range tests {
+		res := calculate(t, Options{},
+			trade("b1", time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC), transaction.Buy, transaction.BTC, "1", transaction.EUR, "20000"),
+			trade("s1", tt.sellTs, transaction.Sell, transaction.BTC, "1", transaction.EUR, "30000"),
+		)
+
+		if res.TaxRecords[0].HoldLongerThenAYear != tt.taxFree {
+			t.Errorf("sell after %s: held longer than a year is %v, want %v",
+				tt.name, res.TaxRecords[0].HoldLongerThenAYear, tt.taxFree)
+		}
+	}
+}
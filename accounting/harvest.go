@@ -0,0 +1,148 @@
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// TaxFreeSoonPeriod is the time before a lot becomes tax free in that
+// realizing its loss is recommended, afterwards the loss can not be offset
+// anymore.
+const TaxFreeSoonPeriod = 30 * 24 * time.Hour
+
+// HarvestCandidate is an open lot that is not tax free and worth less then
+// it was bought for.
+type HarvestCandidate struct {
+	Currency  transaction.Currency
+	Quantity  math.Decimal
+	BuyTs     time.Time
+	TaxFreeTs time.Time
+	BuyPrice  math.Decimal
+	Value     math.Decimal // value at the current price
+	Loss      math.Decimal // BuyPrice - Value
+	// TaxSaved is the estimated tax that is saved by realizing the loss,
+	// additionally to the losses of the candidates ranked before
+	TaxSaved math.Decimal
+	// TaxFreeSoon is true if the lot becomes tax free within
+	// TaxFreeSoonPeriod, the loss can only be used before
+	TaxFreeSoon bool
+}
+
+// HarvestReport contains the lots whose losses can be realized to reduce
+// the taxable profit of a year.
+type HarvestReport struct {
+	At             time.Time
+	TaxRate        math.Decimal
+	RealizedProfit math.Decimal // taxable profit of the year at At
+	Candidates     []*HarvestCandidate
+}
+
+func tax(profit, freigrenze, rate math.Decimal) math.Decimal {
+	if profit.Cmp(freigrenze) < 0 {
+		return math.Decimal{}
+	}
+
+	return profit.Mul(rate)
+}
+
+// Harvest returns the open lots that can be sold at a loss, ranked by the
+// realizable loss. prices contains the current price in € per currency,
+// lots of currencies without price are ignored. taxRate is the personal
+// income tax rate used to estimate the saved tax.
+func (r *Result) Harvest(prices map[transaction.Currency]math.Decimal, at time.Time, taxRate math.Decimal) *HarvestReport {
+	res := HarvestReport{
+		At:             at,
+		TaxRate:        taxRate,
+		RealizedProfit: r.TaxableProfit(at.Year()),
+	}
+
+	for _, lot := range r.Lots {
+		if lot.Balance.Sign() <= 0 {
+			continue
+		}
+
+		if lot.BuyTx.Timestamp.After(at) {
+			continue
+		}
+
+		taxFreeTs := TaxFreeTs(lot.Acquired)
+		if !taxFreeTs.After(at) {
+			continue
+		}
+
+		price, exist := prices[lot.Currency]
+		if !exist {
+			continue
+		}
+
+		value := lot.Balance.Mul(price)
+		if value.Cmp(lot.Cost) >= 0 {
+			continue
+		}
+
+		res.Candidates = append(res.Candidates, &HarvestCandidate{
+			Currency:    lot.Currency,
+			Quantity:    lot.Balance,
+			BuyTs:       lot.Acquired,
+			TaxFreeTs:   taxFreeTs,
+			BuyPrice:    lot.Cost,
+			Value:       value,
+			Loss:        lot.Cost.Sub(value),
+			TaxFreeSoon: taxFreeTs.Sub(at) <= TaxFreeSoonPeriod,
+		})
+	}
+
+	sort.SliceStable(res.Candidates, func(i, j int) bool {
+		return res.Candidates[i].Loss.Cmp(res.Candidates[j].Loss) > 0
+	})
+
+	freigrenze := Freigrenze(at.Year())
+	profit := res.RealizedProfit
+	for _, c := range res.Candidates {
+		before := tax(profit, freigrenze, taxRate)
+		profit = profit.Sub(c.Loss)
+		c.TaxSaved = before.Sub(tax(profit, freigrenze, taxRate))
+	}
+
+	return &res
+}
+
+func (r *HarvestReport) String() string {
+	var buf bytes.Buffer
+
+	buf.Write([]byte(fmt.Sprintf("Taxable Profit %d: %.2f€\n\n", r.At.Year(), r.RealizedProfit)))
+
+	tw := tabwriter.NewWriter(&buf, 0, 4, 4, ' ', 0)
+	tw.Write([]byte("# Currency\tQuantity\tBuy Date\tTax Free Date\tBuy Price\tValue\tLoss\tTax Saved\tNote\n"))
+	for _, c := range r.Candidates {
+		var note string
+		if c.TaxFreeSoon {
+			note = fmt.Sprintf("tax free in %d days", daysUntil(r.At, c.TaxFreeTs))
+		}
+
+		tw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%.2f€\t%.2f€\t%.2f€\t%.2f€\t%s\n",
+			c.Currency,
+			c.Quantity,
+			c.BuyTs.Format(TimeFormat),
+			c.TaxFreeTs.Format(TimeFormat),
+			c.BuyPrice,
+			c.Value,
+			c.Loss,
+			c.TaxSaved,
+			note,
+		)))
+	}
+	tw.Flush()
+
+	buf.Write([]byte(fmt.Sprintf("---\nTax Rate: %.2f%%\n",
+		r.TaxRate.Mul(math.NewDecimal(100, 0)))))
+	buf.Write([]byte("Lots are sold in FIFO order, older lots of the same currency are sold first.\n"))
+
+	return buf.String()
+}
@@ -0,0 +1,83 @@
+package accounting
+
+import (
+	"testing"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestHarvestFreigrenze(t *testing.T) {
+	tests := []struct {
+		name     string
+		profit   string
+		taxSaved string
+	}{
+		// the loss reduces the profit below the Freigrenze, the whole
+		// tax is saved
+		{name: "at Freigrenze", profit: "600", taxSaved: "252"},
+		{name: "below Freigrenze", profit: "599.99", taxSaved: "0"},
+		{name: "above Freigrenze after loss", profit: "800", taxSaved: "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := calculate(t, Options{},
+				trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+				trade("s1", day(10), transaction.Sell, transaction.BTC, "1", transaction.EUR, dec("10000").Add(dec(tt.profit)).String()),
+				trade("b2", day(20), transaction.Buy, transaction.ETH, "1", transaction.EUR, "1000"),
+			)
+
+			report := res.Harvest(map[transaction.Currency]math.Decimal{transaction.ETH: dec("900")}, day(30), dec("0.42"))
+
+			assertDecimal(t, "realized profit", report.RealizedProfit, tt.profit)
+
+			if len(report.Candidates) != 1 {
+				t.Fatalf("got %d candidates, want 1", len(report.Candidates))
+			}
+
+			assertDecimal(t, "loss", report.Candidates[0].Loss, "100")
+			assertDecimal(t, "tax saved", report.Candidates[0].TaxSaved, tt.taxSaved)
+		})
+	}
+}
+
+func TestHarvestCandidates(t *testing.T) {
+	at := day(400)
+
+	res := calculate(t, Options{},
+		// tax free at the time of the report
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		// becomes tax free in 20 days
+		trade("b2", day(400).AddDate(-1, 0, 20), transaction.Buy, transaction.ETH, "1", transaction.EUR, "1000"),
+		trade("b3", day(390), transaction.Buy, transaction.LTC, "10", transaction.EUR, "100"),
+		// worth more then it was bought for
+		trade("b4", day(390), transaction.Buy, transaction.XRP, "100", transaction.EUR, "1"),
+		// no current price
+		trade("b5", day(390), transaction.Buy, transaction.XLM, "100", transaction.EUR, "1"),
+	)
+
+	report := res.Harvest(map[transaction.Currency]math.Decimal{
+		transaction.BTC: dec("1000"),
+		transaction.ETH: dec("900"),
+		transaction.LTC: dec("50"),
+		transaction.XRP: dec("2"),
+	}, at, dec("0.42"))
+
+	if len(report.Candidates) != 2 {
+		t.Fatalf("got %d candidates, want 2", len(report.Candidates))
+	}
+
+	// ranked by loss
+	ltc, eth := report.Candidates[0], report.Candidates[1]
+	if ltc.Currency != transaction.LTC || eth.Currency != transaction.ETH {
+		t.Fatalf("got candidates %s, %s, want LTC, ETH", ltc.Currency, eth.Currency)
+	}
+
+	assertDecimal(t, "LTC loss", ltc.Loss, "500")
+	assertDecimal(t, "ETH loss", eth.Loss, "100")
+
+	if ltc.TaxFreeSoon || !eth.TaxFreeSoon {
+		t.Errorf("tax free soon is %v for LTC and %v for ETH, want false and true", ltc.TaxFreeSoon, eth.TaxFreeSoon)
+	}
+}
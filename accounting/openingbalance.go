@@ -0,0 +1,78 @@
+package accounting
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// OpeningBalanceExchange is the exchange name of the buy records of opening
+// balances.
+const OpeningBalanceExchange = "Opening Balance"
+
+// OpeningBalance is a holding that was acquired before the transactions of
+// the book.
+type OpeningBalance struct {
+	Currency transaction.Currency
+	Quantity math.Decimal
+	Acquired time.Time
+	Cost     math.Decimal // cost basis of Quantity in €
+	Wallet   string
+}
+
+// addOpeningBalances adds a lot per opening balance, in the order they were
+// acquired. The lots are sold before the lots of txs, an error is returned
+// if a balance was acquired after the first transaction of its currency.
+func (r *Result) addOpeningBalances(balances []*OpeningBalance, txs []*transaction.Tx) error {
+	first := map[transaction.Currency]*transaction.Tx{}
+	for _, tx := range txs {
+		for _, cur := range []transaction.Currency{tx.Currency, tx.PayCurrency, tx.FeesIn()} {
+			if _, exist := first[cur]; !exist {
+				first[cur] = tx
+			}
+		}
+	}
+
+	sorted := append([]*OpeningBalance(nil), balances...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Acquired.Before(sorted[j].Acquired)
+	})
+
+	for i, ob := range sorted {
+		if ob.Currency.IsFiat() {
+			return fmt.Errorf("opening balance of %s: fiat currencies have no cost basis", ob.Currency)
+		}
+
+		if ob.Quantity.Sign() <= 0 {
+			return fmt.Errorf("opening balance of %s: quantity %s is not positive", ob.Currency, ob.Quantity)
+		}
+
+		if ob.Cost.Sign() < 0 {
+			return fmt.Errorf("opening balance of %s: cost %s is negative", ob.Currency, ob.Cost)
+		}
+
+		if tx, exist := first[ob.Currency]; exist && ob.Acquired.After(tx.Timestamp) {
+			return fmt.Errorf("opening balance of %s acquired at %s is after the first transaction of the currency: %v, it would be sold before earlier acquisitions",
+				ob.Currency, ob.Acquired.Format(time.RFC3339), tx)
+		}
+
+		buyTx := transaction.Tx{
+			ID:          fmt.Sprintf("opening-balance:%d", i+1),
+			Exchange:    OpeningBalanceExchange,
+			Wallet:      ob.Wallet,
+			Timestamp:   ob.Acquired,
+			Type:        transaction.Buy,
+			PayCurrency: transaction.EUR,
+			Currency:    ob.Currency,
+			Quantity:    ob.Quantity,
+			SpotPrice:   ob.Cost.Quo(ob.Quantity, math.DivScale, math.RoundHalfEven),
+		}
+
+		r.acquire(&buyTx, ob.Currency, ob.Quantity, ob.Cost)
+	}
+
+	return nil
+}
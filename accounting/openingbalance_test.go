@@ -0,0 +1,59 @@
+package accounting
+
+import (
+	"testing"
+
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestOpeningBalances(t *testing.T) {
+	opts := Options{OpeningBalances: []*OpeningBalance{
+		{Currency: transaction.BTC, Quantity: dec("0.5"), Acquired: day(-20), Cost: dec("3000")},
+		{Currency: transaction.BTC, Quantity: dec("1"), Acquired: day(-100), Cost: dec("1000"), Wallet: "ledger"},
+	}}
+
+	res := calculate(t, opts,
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		trade("s1", day(10), transaction.Sell, transaction.BTC, "1.2", transaction.EUR, "20000"),
+	)
+
+	if len(res.TaxRecords) != 2 {
+		t.Fatalf("got %d tax records, want 2", len(res.TaxRecords))
+	}
+
+	// the opening balances are sold first, in the order they were acquired
+	assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(-100), day(10), "20000", "1000", "0")
+	assertTaxRecord(t, res.TaxRecords[1], transaction.BTC, day(-20), day(10), "4000", "1200", "0")
+
+	if res.Lots[0].BuyTx.Exchange != OpeningBalanceExchange || res.Lots[0].BuyTx.Wallet != "ledger" {
+		t.Errorf("lot of the opening balance was bought at %s", res.Lots[0].BuyTx.Account())
+	}
+}
+
+func TestOpeningBalancesInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance OpeningBalance
+	}{
+		{name: "fiat currency", balance: OpeningBalance{Currency: transaction.EUR, Quantity: dec("100"), Acquired: day(-10), Cost: dec("100")}},
+		{name: "negative cost", balance: OpeningBalance{Currency: transaction.BTC, Quantity: dec("1"), Acquired: day(-10), Cost: dec("-1")}},
+		{name: "zero quantity", balance: OpeningBalance{Currency: transaction.BTC, Quantity: dec("0"), Acquired: day(-10), Cost: dec("1")}},
+		{name: "acquisition after the first buy", balance: OpeningBalance{Currency: transaction.BTC, Quantity: dec("1"), Acquired: day(5), Cost: dec("1")}},
+	}
+
+	for _, tt := range tests {
+		ob := tt.balance
+
+		book, err := NewBook([]*transaction.Tx{
+			trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = book.Calculate(Options{OpeningBalances: []*OpeningBalance{&ob}})
+		if err == nil {
+			t.Errorf("calculating with an opening balance with %s succeeded", tt.name)
+		}
+	}
+}
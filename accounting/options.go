@@ -0,0 +1,55 @@
+package accounting
+
+import (
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// Options configure how Book.Calculate matches the transactions. The zero
+// value is a valid configuration.
+type Options struct {
+	// OpeningBalances are booked as lots before the transactions of the
+	// book, they must not be acquired after the first transaction of
+	// their currency
+	OpeningBalances []*OpeningBalance
+
+	// DustThresholds contains per currency the maximal balance of a lot
+	// that is treated as rounding remainder (dust)
+	DustThresholds map[transaction.Currency]math.Decimal
+	// DustThreshold is used for currencies without an entry in
+	// DustThresholds
+	DustThreshold math.Decimal
+	// DustPolicy defines what happens with dust
+	DustPolicy DustPolicy
+
+	// UnmatchedPolicy defines how sells are handled for that no
+	// acquisition exists
+	UnmatchedPolicy UnmatchedPolicy
+	// OpeningPrices contains per currency the cost basis in € per unit
+	// that is used for unmatched sells with UnmatchedOpeningBalance
+	OpeningPrices map[transaction.Currency]math.Decimal
+	// OpeningDate is the acquisition time that is used for unmatched
+	// sells with UnmatchedOpeningBalance
+	OpeningDate time.Time
+
+	// Prices provides the € prices of fiat currencies other than €, they
+	// are required for transactions in e.g. USD markets
+	Prices PriceSource
+}
+
+// PriceSource provides historical prices.
+type PriceSource interface {
+	// At returns the € price per unit of currency at ts, false is
+	// returned if it is unknown
+	At(currency transaction.Currency, ts time.Time) (math.Decimal, bool)
+}
+
+func (o *Options) dustThreshold(currency transaction.Currency) math.Decimal {
+	if threshold, exist := o.DustThresholds[currency]; exist {
+		return threshold
+	}
+
+	return o.DustThreshold
+}
@@ -0,0 +1,217 @@
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// SimulationExchange is the exchange name of simulated transactions.
+const SimulationExchange = "Simulation"
+
+// SimulatedSell is the outcome of a hypothetical sell.
+type SimulatedSell struct {
+	Tx *transaction.Tx
+	// Lots contains a record per consumed buy
+	Lots []*TaxRecord
+	// Uncovered is the quantity for that no buy exists, it is accounted
+	// according to the UnmatchedPolicy
+	Uncovered math.Decimal
+	// TaxableProfit is the profit of the sell that is subject to taxation
+	TaxableProfit math.Decimal
+	// TaxFreeProfit is the profit of lots that were held longer then a
+	// year
+	TaxFreeProfit math.Decimal
+	// YearProfitBefore and YearProfitAfter are the taxable profits of the
+	// tax year of the sell, without and with the simulated sell
+	YearProfitBefore math.Decimal
+	YearProfitAfter  math.Decimal
+	Freigrenze       math.Decimal
+}
+
+// Freigrenze returns the exemption limit for profits of private sales
+// (§23 EStG). If the profit of a year is lower, it is completely tax free.
+func Freigrenze(year int) math.Decimal {
+	if year >= 2024 {
+		return math.NewDecimal(1000, 0)
+	}
+
+	return math.NewDecimal(600, 0)
+}
+
+// TaxableProfit returns the sum of profits and losses of a year that are
+// subject to taxation.
+func (r *Result) TaxableProfit(year int) math.Decimal {
+	var res math.Decimal
+
+	for _, tr := range r.TaxRecords {
+		if tr.TaxYear != year || tr.HoldLongerThenAYear {
+			continue
+		}
+
+		res = res.Add(tr.SellPrice).Sub(tr.BuyPrice).Sub(tr.AdvertisingCosts)
+	}
+
+	return res
+}
+
+// copy returns a copy of the result whose lots can be modified without
+// affecting r.
+func (r *Result) copy() *Result {
+	cp := Result{opts: r.opts, last: r.last}
+
+	lots := map[*Lot]*Lot{}
+	for _, lot := range r.Lots {
+		lotCp := *lot
+		lotCp.Matches = nil
+
+		lots[lot] = &lotCp
+		cp.Lots = append(cp.Lots, &lotCp)
+	}
+
+	for _, m := range r.Matches {
+		mCp := *m
+		mCp.Lot = lots[m.Lot]
+		mCp.Lot.Matches = append(mCp.Lot.Matches, &mCp)
+		cp.Matches = append(cp.Matches, &mCp)
+	}
+
+	cp.Warnings = append(cp.Warnings, r.Warnings...)
+	cp.Unmatched = append(cp.Unmatched, r.Unmatched...)
+
+	for cur, d := range r.dust {
+		if cp.dust == nil {
+			cp.dust = map[transaction.Currency]*dust{}
+		}
+
+		dCp := *d
+		cp.dust[cur] = &dCp
+	}
+
+	return &cp
+}
+
+// SimulateSell calculates the tax consequences of selling quantity of
+// currency for price € per unit at ts, fees are in €.
+// The sell is applied to a copy of the result, r is not modified. ts must
+// not be before the last transaction, the sell would be matched to lots
+// that were acquired after it otherwise.
+func (r *Result) SimulateSell(currency transaction.Currency, quantity, price, fees math.Decimal, ts time.Time) (*SimulatedSell, error) {
+	if currency.IsFiat() {
+		return nil, fmt.Errorf("%s is a fiat currency, only sells of cryptocurrencies can be simulated", currency)
+	}
+
+	if quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("quantity of the simulated sell must be positive: %s", quantity)
+	}
+
+	if fees.Sign() < 0 {
+		return nil, fmt.Errorf("fees of the simulated sell must not be negative: %s", fees)
+	}
+
+	if ts.Before(r.last) {
+		return nil, fmt.Errorf("the simulated sell at %s is before the last transaction at %s",
+			ts.Format(time.RFC3339), r.last.Format(time.RFC3339))
+	}
+
+	tx := transaction.Tx{
+		ID:          "simulated-sell",
+		Exchange:    SimulationExchange,
+		Timestamp:   ts,
+		Type:        transaction.Sell,
+		PayCurrency: transaction.EUR,
+		Currency:    currency,
+		Quantity:    quantity,
+		SpotPrice:   price,
+		Fees:        fees,
+	}
+
+	cp := r.copy()
+	err := cp.apply(&tx)
+	if err != nil {
+		return nil, err
+	}
+	cp.TaxRecords = cp.taxRecords()
+
+	res := SimulatedSell{
+		Tx:               &tx,
+		YearProfitBefore: r.TaxableProfit(ts.Year()),
+		YearProfitAfter:  cp.TaxableProfit(ts.Year()),
+		Freigrenze:       Freigrenze(ts.Year()),
+	}
+
+	// the records are created in the same order as by taxRecords, the
+	// fees of the buys are only included if no previous sell included them
+	includedFees := map[string]interface{}{}
+	for _, m := range cp.Matches {
+		tr := cp.taxRecord(m, includedFees)
+		if m.Tx != &tx || tr == nil {
+			continue
+		}
+		res.Lots = append(res.Lots, tr)
+
+		if tr.HoldLongerThenAYear {
+			res.TaxFreeProfit = res.TaxFreeProfit.Add(m.Profit)
+		}
+	}
+
+	for _, u := range cp.Unmatched {
+		if u.Tx == &tx {
+			res.Uncovered = res.Uncovered.Add(u.Quantity)
+		}
+	}
+
+	res.TaxableProfit = res.YearProfitAfter.Sub(res.YearProfitBefore)
+
+	return &res, nil
+}
+
+func freigrenzeState(profit, freigrenze math.Decimal) string {
+	if profit.Cmp(freigrenze) < 0 {
+		return "below Freigrenze, tax free"
+	}
+
+	return "exceeds Freigrenze, taxable"
+}
+
+func (s *SimulatedSell) String() string {
+	var buf bytes.Buffer
+
+	buf.Write([]byte(fmt.Sprintf("Simulated sell: %s %s for %s€\n\n",
+		s.Tx.Quantity, s.Tx.Currency, s.Tx.SpotPrice)))
+
+	tw := tabwriter.NewWriter(&buf, 0, 4, 4, ' ', 0)
+	tw.Write([]byte("# Buy Date\tHold >=1Year\tSell Price\tBuy Price\tFees\tProfit\n"))
+	for _, tr := range s.Lots {
+		tw.Write([]byte(fmt.Sprintf("%s\t%v\t%.2f€\t%.2f€\t%.2f€\t%.2f€\n",
+			tr.BuyTs.Format(TimeFormat),
+			tr.HoldLongerThenAYear,
+			tr.SellPrice,
+			tr.BuyPrice,
+			tr.AdvertisingCosts,
+			tr.SellPrice.Sub(tr.BuyPrice).Sub(tr.AdvertisingCosts),
+		)))
+	}
+	tw.Flush()
+
+	buf.Write([]byte("---\n"))
+	if s.Uncovered.Sign() > 0 {
+		buf.Write([]byte(fmt.Sprintf("WARNING: no buy records for %s %s\n",
+			s.Uncovered, s.Tx.Currency)))
+	}
+	buf.Write([]byte(fmt.Sprintf("Taxable Profit: %.2f€\n", s.TaxableProfit)))
+	buf.Write([]byte(fmt.Sprintf("Tax Free Profit: %.2f€\n", s.TaxFreeProfit)))
+	buf.Write([]byte(fmt.Sprintf("Freigrenze %d: %.2f€\n", s.Tx.Timestamp.Year(), s.Freigrenze)))
+	buf.Write([]byte(fmt.Sprintf("Taxable Profit %d before: %.2f€ (%s)\n",
+		s.Tx.Timestamp.Year(), s.YearProfitBefore,
+		freigrenzeState(s.YearProfitBefore, s.Freigrenze))))
+	buf.Write([]byte(fmt.Sprintf("Taxable Profit %d after: %.2f€ (%s)\n",
+		s.Tx.Timestamp.Year(), s.YearProfitAfter,
+		freigrenzeState(s.YearProfitAfter, s.Freigrenze))))
+
+	return buf.String()
+}
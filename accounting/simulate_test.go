@@ -0,0 +1,92 @@
+package accounting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestSimulateSell(t *testing.T) {
+	res := calculate(t, Options{},
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		trade("b2", day(400), transaction.Buy, transaction.BTC, "1", transaction.EUR, "30000"),
+	)
+	before := res.String()
+
+	sim, err := res.SimulateSell(transaction.BTC, dec("1.5"), dec("40000"), math.Decimal{}, day(410))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sim.Lots) != 2 {
+		t.Fatalf("got %d lots, want 2", len(sim.Lots))
+	}
+
+	if !sim.Lots[0].HoldLongerThenAYear || sim.Lots[1].HoldLongerThenAYear {
+		t.Errorf("only the first lot must be held longer than a year: %+v, %+v", sim.Lots[0], sim.Lots[1])
+	}
+
+	assertDecimal(t, "taxable profit", sim.TaxableProfit, "5000")
+	assertDecimal(t, "tax free profit", sim.TaxFreeProfit, "30000")
+
+	if res.String() != before {
+		t.Error("the result was modified by the simulation")
+	}
+}
+
+func TestSimulateSellInvalid(t *testing.T) {
+	res := calculate(t, Options{},
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		trade("b2", day(400), transaction.Buy, transaction.BTC, "1", transaction.EUR, "30000"),
+	)
+
+	tests := []struct {
+		name     string
+		currency transaction.Currency
+		quantity string
+		fees     string
+		ts       time.Time
+	}{
+		{name: "before the last transaction", currency: transaction.BTC, quantity: "1", fees: "0", ts: day(200)},
+		{name: "zero quantity", currency: transaction.BTC, quantity: "0", fees: "0", ts: day(410)},
+		{name: "negative quantity", currency: transaction.BTC, quantity: "-1", fees: "0", ts: day(410)},
+		{name: "negative fees", currency: transaction.BTC, quantity: "1", fees: "-1", ts: day(410)},
+		{name: "fiat currency", currency: transaction.EUR, quantity: "1", fees: "0", ts: day(410)},
+	}
+
+	for _, tt := range tests {
+		_, err := res.SimulateSell(tt.currency, dec(tt.quantity), dec("40000"), dec(tt.fees), tt.ts)
+		if err == nil {
+			t.Errorf("simulating a sell with %s succeeded", tt.name)
+		}
+	}
+}
+
+func TestSimulateSellFees(t *testing.T) {
+	buy := trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000")
+	buy.Fees = dec("20")
+
+	res := calculate(t, Options{}, buy)
+
+	sim, err := res.SimulateSell(transaction.BTC, dec("1"), dec("12000"), dec("30"), day(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sim.Lots) != 1 {
+		t.Fatalf("got %d lots, want 1", len(sim.Lots))
+	}
+
+	// the fees of the buy and the sell are advertising costs, like in
+	// the tax report
+	assertDecimal(t, "advertising costs", sim.Lots[0].AdvertisingCosts, "50")
+	assertDecimal(t, "taxable profit", sim.TaxableProfit, "1950")
+
+	s1 := trade("s1", day(10), transaction.Sell, transaction.BTC, "1", transaction.EUR, "12000")
+	s1.Fees = dec("30")
+
+	report := calculate(t, Options{}, buy, s1)
+	assertDecimal(t, "taxable profit of the report", report.TaxableProfit(day(10).Year()), "1950")
+}
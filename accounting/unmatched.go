@@ -0,0 +1,157 @@
+package accounting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// UnmatchedPolicy defines how sold quantities are handled for that no
+// acquisition exists.
+type UnmatchedPolicy int
+
+const (
+	// UnmatchedZeroBasis accounts the quantity as bought for 0€ at the
+	// time of the sell, the whole proceeds are taxable
+	UnmatchedZeroBasis UnmatchedPolicy = iota
+	// UnmatchedFail aborts the calculation with an error
+	UnmatchedFail
+	// UnmatchedOpeningBalance accounts the quantity as bought at
+	// Options.OpeningDate for the price in Options.OpeningPrices
+	UnmatchedOpeningBalance
+)
+
+var strToUnmatchedPolicy = map[string]UnmatchedPolicy{
+	"zero-basis":      UnmatchedZeroBasis,
+	"fail":            UnmatchedFail,
+	"opening-balance": UnmatchedOpeningBalance,
+}
+
+var unmatchedPolicyToStr = map[UnmatchedPolicy]string{
+	UnmatchedZeroBasis:      "zero-basis",
+	UnmatchedFail:           "fail",
+	UnmatchedOpeningBalance: "opening-balance",
+}
+
+var ErrUndefinedUnmatchedPolicy = errors.New("unsupported policy for unmatched sells")
+
+func NewUnmatchedPolicy(policy string) (UnmatchedPolicy, error) {
+	res, ok := strToUnmatchedPolicy[strings.ToLower(policy)]
+	if !ok {
+		return 0, ErrUndefinedUnmatchedPolicy
+	}
+
+	return res, nil
+}
+
+func (p UnmatchedPolicy) String() string {
+	res, ok := unmatchedPolicyToStr[p]
+	if !ok {
+		return "undefined"
+	}
+
+	return res
+}
+
+// UnmatchedExchange is the exchange name of the buy records that are
+// created for unmatched sells.
+const UnmatchedExchange = "Unmatched"
+
+// UnmatchedSell is a sold quantity for that no acquisition exists.
+type UnmatchedSell struct {
+	Tx       *transaction.Tx
+	Currency transaction.Currency
+	Quantity math.Decimal
+	Policy   UnmatchedPolicy
+	Cost     math.Decimal // assumed cost basis in €
+	BuyTs    time.Time    // assumed time of acquisition
+}
+
+// unmatchedLot creates a lot for quantity of currency that is sold by tx
+// and for that no acquisition exists, according to the UnmatchedPolicy.
+func (r *Result) unmatchedLot(tx *transaction.Tx, currency transaction.Currency, quantity math.Decimal) (*Lot, error) {
+	u := UnmatchedSell{
+		Tx:       tx,
+		Currency: currency,
+		Quantity: quantity,
+		Policy:   r.opts.UnmatchedPolicy,
+		BuyTs:    tx.Timestamp,
+	}
+
+	switch r.opts.UnmatchedPolicy {
+	case UnmatchedFail:
+		return nil, fmt.Errorf("could not find buy record for %s %s of %v", quantity, currency, tx)
+
+	case UnmatchedOpeningBalance:
+		price, exist := r.opts.OpeningPrices[currency]
+		if !exist {
+			return nil, fmt.Errorf("could not find buy record for %s %s of %v and no opening price for %s is defined",
+				quantity, currency, tx, currency)
+		}
+
+		u.Cost = quantity.Mul(price)
+		u.BuyTs = r.opts.OpeningDate
+	}
+
+	log.Warnf("accounting: could not find buy record for %s %s of %v, accounting it as %s",
+		quantity, currency, tx, u.Policy)
+
+	buyTx := transaction.Tx{
+		ID:          "unmatched:" + tx.ID,
+		Exchange:    UnmatchedExchange,
+		Timestamp:   u.BuyTs,
+		Type:        transaction.Buy,
+		PayCurrency: transaction.EUR,
+		Currency:    currency,
+		Quantity:    quantity,
+		SpotPrice:   u.Cost.Quo(quantity, math.DivScale, math.RoundHalfEven),
+	}
+
+	lot := Lot{
+		Currency: currency,
+		Quantity: quantity,
+		Balance:  quantity,
+		Cost:     u.Cost,
+		BuyTx:    &buyTx,
+		Acquired: u.BuyTs,
+	}
+
+	r.Lots = append(r.Lots, &lot)
+	r.Unmatched = append(r.Unmatched, &u)
+
+	return &lot, nil
+}
+
+// DiagnosticsReport lists the sells for that no acquisition exists and how
+// they were accounted.
+func (r *Result) DiagnosticsReport() string {
+	var buf bytes.Buffer
+
+	tw := tabwriter.NewWriter(&buf, 0, 4, 4, ' ', 0)
+	tw.Write([]byte("# Sell Date\tExchange\tExchange TX ID\tCurrency\tUnmatched Quantity\tAccounted As\tAssumed Buy Date\tAssumed Cost\n"))
+
+	for _, u := range r.Unmatched {
+		tw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%.2f€\n",
+			u.Tx.Timestamp.Format(TimeFormat),
+			u.Tx.Account(),
+			u.Tx.ID,
+			u.Currency,
+			u.Quantity,
+			u.Policy,
+			u.BuyTs.Format(TimeFormat),
+			u.Cost,
+		)))
+	}
+
+	tw.Flush()
+	buf.Write([]byte(fmt.Sprintf("---\nCount: %d\n", len(r.Unmatched))))
+
+	return buf.String()
+}
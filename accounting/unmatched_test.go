@@ -0,0 +1,115 @@
+package accounting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// unmatchedRecords sell 0.5 BTC more then were bought.
+func unmatchedRecords() []*transaction.Tx {
+	return []*transaction.Tx{
+		trade("b1", day(0), transaction.Buy, transaction.BTC, "1", transaction.EUR, "10000"),
+		trade("s1", day(10), transaction.Sell, transaction.BTC, "1.5", transaction.EUR, "20000"),
+	}
+}
+
+func TestUnmatchedZeroBasis(t *testing.T) {
+	res := calculate(t, Options{UnmatchedPolicy: UnmatchedZeroBasis}, unmatchedRecords()...)
+
+	if len(res.TaxRecords) != 2 {
+		t.Fatalf("got %d tax records, want 2", len(res.TaxRecords))
+	}
+
+	// the unmatched quantity is bought for 0€ at the time of the sell
+	assertTaxRecord(t, res.TaxRecords[0], transaction.BTC, day(0), day(10), "20000", "10000", "0")
+	assertTaxRecord(t, res.TaxRecords[1], transaction.BTC, day(10), day(10), "10000", "0", "0")
+	assertDecimal(t, "taxable profit", res.TaxableProfit(day(10).Year()), "20000")
+
+	if len(res.Unmatched) != 1 {
+		t.Fatalf("got %d unmatched sells, want 1", len(res.Unmatched))
+	}
+
+	u := res.Unmatched[0]
+	if u.Tx.ID != "s1" || u.Policy != UnmatchedZeroBasis {
+		t.Errorf("unmatched sell is %s accounted as %s, want s1 as %s", u.Tx.ID, u.Policy, UnmatchedZeroBasis)
+	}
+	assertDecimal(t, "unmatched quantity", u.Quantity, "0.5")
+	assertDecimal(t, "unmatched cost", u.Cost, "0")
+
+	report := res.DiagnosticsReport()
+	if !strings.Contains(report, "zero-basis") || !strings.Contains(report, "Count: 1") {
+		t.Errorf("diagnostics report does not list the unmatched sell:\n%s", report)
+	}
+}
+
+func TestUnmatchedOpeningBalance(t *testing.T) {
+	opts := Options{
+		UnmatchedPolicy: UnmatchedOpeningBalance,
+		OpeningPrices:   map[transaction.Currency]math.Decimal{transaction.BTC: dec("5000")},
+		OpeningDate:     day(-500),
+	}
+
+	res := calculate(t, opts, unmatchedRecords()...)
+
+	if len(res.TaxRecords) != 2 {
+		t.Fatalf("got %d tax records, want 2", len(res.TaxRecords))
+	}
+
+	assertTaxRecord(t, res.TaxRecords[1], transaction.BTC, day(-500), day(10), "10000", "2500", "0")
+
+	if !res.TaxRecords[1].HoldLongerThenAYear {
+		t.Error("the unmatched quantity acquired at the opening date is not tax free")
+	}
+
+	if len(res.Unmatched) != 1 || res.Unmatched[0].Policy != UnmatchedOpeningBalance {
+		t.Fatalf("got unmatched sells %v, want one accounted as %s", res.Unmatched, UnmatchedOpeningBalance)
+	}
+	assertDecimal(t, "unmatched cost", res.Unmatched[0].Cost, "2500")
+}
+
+func TestUnmatchedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "fail", opts: Options{UnmatchedPolicy: UnmatchedFail}},
+		{
+			name: "opening balance without price",
+			opts: Options{
+				UnmatchedPolicy: UnmatchedOpeningBalance,
+				OpeningPrices:   map[transaction.Currency]math.Decimal{transaction.ETH: dec("100")},
+				OpeningDate:     day(-500),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := NewBook(unmatchedRecords())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = book.Calculate(tt.opts)
+			if err == nil {
+				t.Error("Calculate succeeded")
+			}
+		})
+	}
+}
+
+func TestNewUnmatchedPolicy(t *testing.T) {
+	for _, p := range []UnmatchedPolicy{UnmatchedZeroBasis, UnmatchedFail, UnmatchedOpeningBalance} {
+		got, err := NewUnmatchedPolicy(strings.ToUpper(p.String()))
+		if err != nil || got != p {
+			t.Errorf("NewUnmatchedPolicy(%q) = %s, %v, want %s", strings.ToUpper(p.String()), got, err, p)
+		}
+	}
+
+	if _, err := NewUnmatchedPolicy("ignore"); err != ErrUndefinedUnmatchedPolicy {
+		t.Errorf("NewUnmatchedPolicy(ignore) returned error %v, want %v", err, ErrUndefinedUnmatchedPolicy)
+	}
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/fho/cryptotax/accounting"
+	"github.com/fho/cryptotax/config"
+	"github.com/fho/cryptotax/import/etherscan"
+	"github.com/fho/cryptotax/ledger"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// defaultTaxYear returns the tax year of the config or the previous year.
+func defaultTaxYear(cfg *config.Config) uint {
+	if cfg.Report.TaxYear != 0 {
+		return uint(cfg.Report.TaxYear)
+	}
+
+	return uint(time.Now().Year()) - 1
+}
+
+func defaultTaxRate(cfg *config.Config) string {
+	if cfg.Report.TaxRate != nil {
+		return cfg.Report.TaxRate.String()
+	}
+
+	return "0.42"
+}
+
+// diagnostics returns the sections about problems and notes of the
+// calculation, it is empty if there are none.
+func (in *input) diagnostics() string {
+	var buf bytes.Buffer
+
+	if len(in.res.Unmatched) > 0 {
+		buf.WriteString("================\n")
+		buf.WriteString("SELLS WITHOUT BUY RECORDS\n")
+		buf.WriteString(in.res.DiagnosticsReport())
+		buf.WriteString("\n")
+	}
+
+	if len(in.duplicates) > 0 {
+		buf.WriteString("================\n")
+		buf.WriteString("DROPPED DUPLICATE TRANSACTIONS\n")
+		for _, dup := range in.duplicates {
+			fmt.Fprintf(&buf, "%s\t%s\n", dup.ID, dup)
+		}
+		buf.WriteString("\n")
+	}
+
+	if len(in.annotations) > 0 {
+		buf.WriteString("================\n")
+		buf.WriteString("NOTES\n")
+		for _, a := range in.annotations {
+			fmt.Fprintf(&buf, "%s %s: %s\n", a.Exchange, a.ID, a.Note)
+		}
+		buf.WriteString("\n")
+	}
+
+	if len(in.res.Warnings) > 0 {
+		buf.WriteString("================\n")
+		buf.WriteString("WARNINGS\n")
+		buf.WriteString(in.res.WarningsReport())
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
+
+// importFiles imports exchange files into the ledger.
+func importFiles(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var files exchangeFiles
+	var out outputFlag
+	var dataDirFlag string
+
+	fs := newFlagSet("import", "Imports exchange files into the ledger")
+	lf.register(fs)
+	out.register(fs)
+	files.register(fs, "")
+	fs.StringVar(&dataDirFlag, "data-dir", dataDir(cfg), "directory of the transaction ledger")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	if files.empty() {
+		for _, a := range cfg.Accounts {
+			err := files.add(a)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if files.empty() {
+		return usagef(fs, "You have to specify the path to at least 1 csv file or configure accounts in the config file")
+	}
+
+	l, err := ledger.Open(dataDirFlag)
+	if err != nil {
+		return err
+	}
+	etherscan.RegisterTokens(l.Transactions())
+
+	var buf bytes.Buffer
+
+	err = files.read(func(src *fileSource, file *taggedFile, records []*transaction.Tx) error {
+		res, err := l.Import(file.path, src.exchange, file.wallet, records)
+		if err != nil {
+			return err
+		}
+
+		if res.AlreadyImported {
+			fmt.Fprintf(&buf, "%s: already imported at %s\n", file.path, res.Source.ImportedAt.Format(time.RFC3339))
+			return nil
+		}
+
+		fmt.Fprintf(&buf, "%s: %d transactions added, %d duplicates skipped\n", file.path, len(res.Added), len(res.Duplicates))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = l.Save()
+	if err != nil {
+		return err
+	}
+
+	return out.write(buf.String())
+}
+
+// annotate adds a note to a transaction in the ledger.
+func annotate(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var dataDirFlag string
+	var exchangeFlag string
+	var idFlag string
+	var noteFlag string
+
+	fs := newFlagSet("annotate", "Adds a note to a transaction in the ledger")
+	lf.register(fs)
+	fs.StringVar(&dataDirFlag, "data-dir", dataDir(cfg), "directory of the transaction ledger")
+	fs.StringVar(&exchangeFlag, "exchange", "", "exchange of the transaction, optional")
+	fs.StringVar(&idFlag, "id", "", "id of the transaction")
+	fs.StringVar(&noteFlag, "note", "", "note about the transaction")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	if len(idFlag) == 0 || len(noteFlag) == 0 {
+		return usagef(fs, "-id and -note are required")
+	}
+
+	l, err := ledger.Open(dataDirFlag)
+	if err != nil {
+		return err
+	}
+
+	err = l.Annotate(exchangeFlag, idFlag, noteFlag)
+	if err != nil {
+		return err
+	}
+
+	return l.Save()
+}
+
+// report prints the tax report of a year.
+func report(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var in inputFlags
+	var out outputFlag
+	var taxYear uint
+	var fullFlag bool
+
+	fs := newFlagSet("report", "Prints the tax report of a year")
+	lf.register(fs)
+	in.register(fs, cfg)
+	out.register(fs)
+	fs.UintVar(&taxYear, "tax-year", defaultTaxYear(cfg), "year for that the report is created")
+	fs.BoolVar(&fullFlag, "full", false, "report the sells of all years, including tax free ones")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	calc, err := in.calculate()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	if fullFlag {
+		buf.WriteString("TAX REPORT Full\n")
+		buf.WriteString(calc.res.TaxReport(0))
+	} else {
+		fmt.Fprintf(&buf, "TAX REPORT %v\n", taxYear)
+		buf.WriteString(calc.res.TaxReport(int(taxYear)))
+	}
+	buf.WriteString("\n")
+	buf.WriteString(calc.diagnostics())
+
+	return out.write(buf.String())
+}
+
+// holdings prints when the current holdings become tax free and which lots
+// can be sold at a loss.
+func holdings(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var in inputFlags
+	var out outputFlag
+	var harvestPricesFlag string
+	var taxRateFlag string
+
+	fs := newFlagSet("holdings", "Prints when the current holdings become tax free")
+	lf.register(fs)
+	in.register(fs, cfg)
+	out.register(fs)
+	fs.StringVar(&harvestPricesFlag, "harvest-prices", "", "additionally print lots that can be sold at a loss at the given prices, format: BTC=30000,ETH=2000, the prices of -price-source are used if it is not passed")
+	fs.StringVar(&taxRateFlag, "tax-rate", defaultTaxRate(cfg), "personal income tax rate, used to estimate saved taxes")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	var prices map[transaction.Currency]math.Decimal
+	var err error
+	now := time.Now()
+
+	rate, err := math.ParseDecimal(taxRateFlag)
+	if err != nil {
+		return usagef(fs, "parsing tax rate failed: %s", err)
+	}
+
+	// the currencies of the ledger and the imported files are registered
+	// by reading them, the prices can only be parsed afterwards
+	calc, err := in.calculate()
+	if err != nil {
+		return err
+	}
+
+	if len(harvestPricesFlag) != 0 {
+		prices, err = parseCurrencyValues(harvestPricesFlag)
+		if err != nil {
+			return usagef(fs, "%s", err)
+		}
+	} else {
+		src, err := in.prices.source()
+		if err != nil {
+			return err
+		}
+
+		if src != nil {
+			prices = src.AllAt(now)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "TAX FREE FORECAST %s\n", now.Format(accounting.TimeFormat))
+	buf.WriteString(calc.res.TaxFreeReport(now))
+
+	if prices != nil {
+		buf.WriteString("\n")
+		buf.WriteString("TAX LOSS HARVESTING\n")
+		buf.WriteString(calc.res.Harvest(prices, now, rate).String())
+	}
+
+	return out.write(buf.String())
+}
+
+// lots prints all lots with the sells that were matched to them.
+func lots(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var in inputFlags
+	var out outputFlag
+
+	fs := newFlagSet("lots", "Prints all lots and the sells that were matched to them")
+	lf.register(fs)
+	in.register(fs, cfg)
+	out.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	calc, err := in.calculate()
+	if err != nil {
+		return err
+	}
+
+	return out.write(calc.res.String() + "\n")
+}
+
+// simulate prints the tax consequences of a hypothetical sell.
+func simulate(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var in inputFlags
+	var out outputFlag
+	var currencyFlag string
+	var quantityFlag string
+	var priceFlag string
+	var feesFlag string
+	var dateFlag string
+
+	fs := newFlagSet("simulate", "Prints the tax consequences of a hypothetical sell")
+	lf.register(fs)
+	in.register(fs, cfg)
+	out.register(fs)
+	fs.StringVar(&currencyFlag, "currency", "", "currency that is sold")
+	fs.StringVar(&quantityFlag, "quantity", "", "quantity of the simulated sell")
+	fs.StringVar(&priceFlag, "price", "", "price in € per unit of the simulated sell, the price of -price-source at the date is used if it is not passed")
+	fs.StringVar(&feesFlag, "fees", "0", "fees in € of the simulated sell")
+	fs.StringVar(&dateFlag, "date", "", "date or RFC3339 timestamp of the simulated sell, a date is the end of the day (default: now)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	quantity, err := math.ParseDecimal(quantityFlag)
+	if err != nil {
+		return usagef(fs, "parsing quantity failed: %s", err)
+	}
+
+	fees, err := math.ParseDecimal(feesFlag)
+	if err != nil {
+		return usagef(fs, "parsing fees failed: %s", err)
+	}
+
+	ts := time.Now()
+	if len(dateFlag) != 0 {
+		ts, err = parseSimulationTime(dateFlag)
+		if err != nil {
+			return usagef(fs, "parsing date %q failed: %s", dateFlag, err)
+		}
+	}
+
+	// the currencies of the imported files are registered by reading them
+	calc, err := in.calculate()
+	if err != nil {
+		return err
+	}
+
+	cur, err := transaction.NewCurrency(currencyFlag)
+	if err != nil {
+		return usagef(fs, "parsing currency %q failed: %s", currencyFlag, err)
+	}
+
+	var price math.Decimal
+	if len(priceFlag) != 0 {
+		price, err = math.ParseDecimal(priceFlag)
+		if err != nil {
+			return usagef(fs, "parsing price failed: %s", err)
+		}
+	} else {
+		src, err := in.prices.source()
+		if err != nil {
+			return err
+		}
+
+		var exist bool
+		if src != nil {
+			price, exist = src.At(cur, ts)
+		}
+
+		if !exist {
+			return usagef(fs, "-price is required, no price source contains a price of %s at %s", cur, ts.Format(time.RFC3339))
+		}
+	}
+
+	sim, err := calc.res.SimulateSell(cur, quantity, price, fees, ts)
+	if err != nil {
+		return err
+	}
+
+	return out.write(sim.String() + "\n")
+}
+
+// parseSimulationTime parses a RFC3339 timestamp or a date in the local time
+// zone. The time of a date is the end of the day, the sell is simulated
+// after the transactions of the day.
+func parseSimulationTime(v string) (time.Time, error) {
+	ts, err := time.Parse(time.RFC3339, v)
+	if err == nil {
+		return ts, nil
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return day.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// validate checks the transactions for problems. It returns
+// errValidationFailed if any are found.
+func validate(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var in inputFlags
+	var out outputFlag
+
+	fs := newFlagSet("validate", "Checks the transactions for sells without buy records, duplicates and other problems")
+	lf.register(fs)
+	in.register(fs, cfg)
+	out.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	calc, err := in.calculate()
+	if err != nil {
+		return err
+	}
+
+	if len(calc.res.Unmatched) == 0 && len(calc.duplicates) == 0 && len(calc.res.Warnings) == 0 {
+		return out.write("no problems found\n")
+	}
+
+	// notes are not problems, they are only shown by report
+	calc.annotations = nil
+
+	err = out.write(calc.diagnostics())
+	if err != nil {
+		return err
+	}
+
+	return errValidationFailed
+}
+
+// export writes the tax records as CSV.
+func export(cfg *config.Config, args []string) error {
+	var lf logFlags
+	var in inputFlags
+	var out outputFlag
+	var taxYear uint
+
+	fs := newFlagSet("export", "Writes the tax records of a year as csv")
+	lf.register(fs)
+	in.register(fs, cfg)
+	out.register(fs)
+	fs.UintVar(&taxYear, "tax-year", defaultTaxYear(cfg), "year of the exported tax records, 0 exports all years")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	lf.apply()
+
+	calc, err := in.calculate()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	err = calc.res.WriteTaxRecordsCSV(&buf, int(taxYear))
+	if err != nil {
+		return err
+	}
+
+	return out.write(buf.String())
+}
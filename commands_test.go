@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/config"
+)
+
+// tokenLedger is a ledger with a buy of the token TKNA at %[1]s, that is
+// only registered as currency when the ledger is opened.
+const tokenLedger = `{
+  "currencies": ["TKNA"],
+  "sources": [],
+  "entries": [
+    {
+      "tx": {
+        "ID": "0xabc-0",
+        "Exchange": "Etherscan",
+        "Wallet": "0x1111111111111111111111111111111111111111",
+        "Timestamp": "%[1]s",
+        "Type": "buy",
+        "PayCurrency": "EUR",
+        "Currency": "TKNA",
+        "Quantity": "10",
+        "SpotPrice": "2"
+      },
+      "source": "",
+      "imported_at": "%[1]s"
+    }
+  ]
+}`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHoldingsPricesOfRegisteredToken(t *testing.T) {
+	// the lot must not be tax free to be sold at a loss
+	bought := time.Now().AddDate(0, -1, 0).UTC()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ledger.json"), fmt.Sprintf(tokenLedger, bought.Format(time.RFC3339)))
+
+	pricePath := filepath.Join(dir, "prices.csv")
+	writeFile(t, pricePath, "date,currency,price\n"+time.Now().Format("2006-01-02")+",TKNA,1\n")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "harvest prices", args: []string{"-harvest-prices", "TKNA=1"}},
+		{name: "price source", args: []string{"-price-source", pricePath}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outPath := filepath.Join(dir, "out.txt")
+			args := append([]string{"-data-dir", dir, "-o", outPath}, tt.args...)
+
+			err := holdings(&config.Config{}, args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			harvest := strings.SplitN(string(out), "TAX LOSS HARVESTING", 2)
+			if len(harvest) != 2 || !strings.Contains(harvest[1], "TKNA") {
+				t.Errorf("the loss of TKNA is missing in the output:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestManualTransactionIsDeduplicated(t *testing.T) {
+	bought := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC).Format(time.RFC3339)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ledger.json"), fmt.Sprintf(tokenLedger, bought))
+
+	// the manual transaction duplicates the one in the ledger
+	manualPath := filepath.Join(dir, "manual.json")
+	writeFile(t, manualPath, `{"transactions": [{
+		"id": "0xabc-0",
+		"exchange": "Etherscan",
+		"wallet": "0x1111111111111111111111111111111111111111",
+		"timestamp": "`+bought+`",
+		"type": "buy",
+		"currency": "TKNA",
+		"quantity": "10",
+		"spot_price": "2"
+	}]}`)
+
+	var in inputFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	in.register(fs, &config.Config{})
+
+	err := fs.Parse([]string{"-data-dir", dir, "-manual", manualPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calc, err := in.calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calc.duplicates) != 1 {
+		t.Errorf("got %d duplicates, want 1", len(calc.duplicates))
+	}
+
+	if len(calc.res.Lots) != 1 {
+		t.Errorf("got %d lots, want 1, the manual duplicate was booked", len(calc.res.Lots))
+	}
+}
+
+func TestImportOutput(t *testing.T) {
+	const path = "import/bitfinex/testdata/trades.csv"
+
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "out.txt")
+	args := []string{"-data-dir", dir, "-o", outPath, "-bitfinex-csv", path}
+
+	for _, want := range []string{
+		path + ": 4 transactions added, 0 duplicates skipped\n",
+		path + ": already imported at ",
+	} {
+		err := importFiles(&config.Config{}, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(string(out), want) {
+			t.Errorf("got output %q, want it to start with %q", out, want)
+		}
+	}
+}
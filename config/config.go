@@ -0,0 +1,188 @@
+// Package config reads the user settings from a JSON file:
+//
+//	{
+//	  "data_dir": "~/cryptotax",
+//	  "jurisdiction": "DE",
+//	  "cost_basis_method": "fifo",
+//	  "base_currency": "EUR",
+//	  "price_sources": ["prices/*.csv"],
+//	  "manual": "manual.json",
+//	  "opening_balances": "opening-balances.csv",
+//	  "accounts": [
+//	    {"name": "main", "importer": "kraken", "files": ["exports/kraken-main/"]},
+//	    {"importer": "coinbase", "files": ["exports/coinbase-*.csv"]}
+//	  ],
+//	  "report": {
+//	    "tax_year": 2021,
+//	    "tax_rate": "0.42",
+//	    "dust": {"BTC": "0.00000001"},
+//	    "dust_policy": "carry",
+//	    "unmatched_sells": "fail"
+//	  }
+//	}
+//
+// All settings are optional. Relative paths are relative to the directory of
+// the config file, a leading ~/ is replaced by the home directory.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fho/cryptotax/math"
+)
+
+// Supported values of the settings that only have one implementation.
+const (
+	JurisdictionDE = "DE"
+	CostBasisFIFO  = "fifo"
+	BaseCurrency   = "EUR"
+)
+
+// Account is an account or wallet at an exchange and its export files.
+type Account struct {
+	// Name is the wallet tag of the transactions, it is optional
+	Name string `json:"name"`
+	// Importer is the name of the importer for the files, e.g. kraken
+	Importer string `json:"importer"`
+	// Files are paths, globs or directories of the exported files
+	Files []string `json:"files"`
+	// Mapping is the path to the column mapping of the generic importer
+	Mapping string `json:"mapping"`
+}
+
+// Report contains the defaults of the report flags.
+type Report struct {
+	// TaxYear is the default tax year, 0 is the previous year
+	TaxYear        int                     `json:"tax_year"`
+	TaxRate        *math.Decimal           `json:"tax_rate"`
+	Dust           map[string]math.Decimal `json:"dust"`
+	DustPolicy     string                  `json:"dust_policy"`
+	UnmatchedSells string                  `json:"unmatched_sells"`
+}
+
+// Config are the user settings.
+type Config struct {
+	DataDir         string     `json:"data_dir"`
+	Jurisdiction    string     `json:"jurisdiction"`
+	CostBasisMethod string     `json:"cost_basis_method"`
+	BaseCurrency    string     `json:"base_currency"`
+	PriceSources    []string   `json:"price_sources"`
+	Manual          string     `json:"manual"`
+	OpeningBalances string     `json:"opening_balances"`
+	Accounts        []*Account `json:"accounts"`
+	Report          Report     `json:"report"`
+}
+
+// DefaultPath returns the default path of the config file,
+// e.g. ~/.config/cryptotax/config.json.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "cryptotax", "config.json"), nil
+}
+
+// Load reads the config file at path. If the file does not exist and
+// mustExist is false, an empty config is returned.
+func Load(path string, mustExist bool) (*Config, error) {
+	var res Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !mustExist && errors.Is(err, os.ErrNotExist) {
+			return &res, nil
+		}
+
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %s", path, err)
+	}
+
+	err = res.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	res.resolvePaths(filepath.Dir(path))
+
+	return &res, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Jurisdiction) != 0 && !strings.EqualFold(c.Jurisdiction, JurisdictionDE) {
+		return fmt.Errorf("unsupported jurisdiction %q, only %s is supported", c.Jurisdiction, JurisdictionDE)
+	}
+
+	if len(c.CostBasisMethod) != 0 && !strings.EqualFold(c.CostBasisMethod, CostBasisFIFO) {
+		return fmt.Errorf("unsupported cost basis method %q, only %s is supported", c.CostBasisMethod, CostBasisFIFO)
+	}
+
+	if len(c.BaseCurrency) != 0 && !strings.EqualFold(c.BaseCurrency, BaseCurrency) {
+		return fmt.Errorf("unsupported base currency %q, only %s is supported", c.BaseCurrency, BaseCurrency)
+	}
+
+	for i, a := range c.Accounts {
+		if len(a.Importer) == 0 {
+			return fmt.Errorf("account %d has no importer", i+1)
+		}
+
+		if len(a.Files) == 0 {
+			return fmt.Errorf("account %d has no files", i+1)
+		}
+
+		if strings.EqualFold(a.Importer, "generic") && len(a.Mapping) == 0 {
+			return fmt.Errorf("account %d has no mapping, it is required by the generic importer", i+1)
+		}
+	}
+
+	return nil
+}
+
+// resolvePath returns path relative to dir, ~/ is replaced by the home
+// directory.
+func resolvePath(dir, path string) string {
+	if len(path) == 0 {
+		return path
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+func (c *Config) resolvePaths(dir string) {
+	c.DataDir = resolvePath(dir, c.DataDir)
+	c.Manual = resolvePath(dir, c.Manual)
+	c.OpeningBalances = resolvePath(dir, c.OpeningBalances)
+
+	for i, p := range c.PriceSources {
+		c.PriceSources[i] = resolvePath(dir, p)
+	}
+
+	for _, a := range c.Accounts {
+		for i, p := range a.Files {
+			a.Files[i] = resolvePath(dir, p)
+		}
+
+		a.Mapping = resolvePath(dir, a.Mapping)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fho/cryptotax/config"
+	"github.com/fho/cryptotax/import/bitcoincore"
+	"github.com/fho/cryptotax/import/bitfinex"
+	"github.com/fho/cryptotax/import/bitpanda"
+	"github.com/fho/cryptotax/import/bitstamp"
+	"github.com/fho/cryptotax/import/coinbase"
+	"github.com/fho/cryptotax/import/coinbasepro"
+	"github.com/fho/cryptotax/import/cointracking"
+	"github.com/fho/cryptotax/import/electrum"
+	"github.com/fho/cryptotax/import/etherscan"
+	"github.com/fho/cryptotax/import/gemini"
+	"github.com/fho/cryptotax/import/generic"
+	"github.com/fho/cryptotax/import/koinly"
+	"github.com/fho/cryptotax/import/kraken"
+	"github.com/fho/cryptotax/import/kucoin"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// fileArg is a value of a file flag. The pattern is a path, a glob or a
+// directory, wallet is the optional account or wallet tag of the files.
+type fileArg struct {
+	wallet  string
+	pattern string
+}
+
+// fileFlag is a flag that can be passed multiple times. Its values have the
+// format [wallet=]path, path can be a file, a glob or a directory of csv
+// files.
+type fileFlag []fileArg
+
+func (f *fileFlag) String() string {
+	var res []string
+
+	for _, a := range *f {
+		if len(a.wallet) != 0 {
+			res = append(res, a.wallet+"="+a.pattern)
+			continue
+		}
+
+		res = append(res, a.pattern)
+	}
+
+	return strings.Join(res, ",")
+}
+
+func (f *fileFlag) Set(v string) error {
+	var a fileArg
+
+	// a '=' in a path is not a tag separator
+	if idx := strings.IndexByte(v, '='); idx > 0 && !strings.ContainsAny(v[:idx], `/\`) {
+		a.wallet, a.pattern = v[:idx], v[idx+1:]
+	} else {
+		a.pattern = v
+	}
+
+	if len(a.pattern) == 0 {
+		return fmt.Errorf("path of %q is empty", v)
+	}
+
+	*f = append(*f, a)
+
+	return nil
+}
+
+// taggedFile is a file that is imported with a wallet tag.
+type taggedFile struct {
+	wallet string
+	path   string
+}
+
+// expand returns the files that the pattern of a refers to. Directories
+// are expanded to the csv files that they contain, globs to the matching
+// files.
+func (a fileArg) expand() ([]string, error) {
+	fi, err := os.Stat(a.pattern)
+	if err == nil {
+		if !fi.IsDir() {
+			return []string{a.pattern}, nil
+		}
+
+		res, err := filepath.Glob(filepath.Join(a.pattern, "*.csv"))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(res) == 0 {
+			return nil, fmt.Errorf("directory %s does not contain csv files", a.pattern)
+		}
+
+		return res, nil
+	}
+
+	res, globErr := filepath.Glob(a.pattern)
+	if globErr != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", a.pattern, globErr)
+	}
+
+	if len(res) == 0 {
+		return nil, err
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// files returns the files that the flag values refer to, files that are
+// referred to multiple times are only returned once.
+func (f fileFlag) files() ([]*taggedFile, error) {
+	var res []*taggedFile
+	seen := map[string]bool{}
+
+	for _, a := range f {
+		paths, err := a.expand()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range paths {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+
+			res = append(res, &taggedFile{wallet: a.wallet, path: p})
+		}
+	}
+
+	return res, nil
+}
+
+type importer interface {
+	FromCSV(path string) ([]*transaction.Tx, error)
+}
+
+// fileSource are the files of an exchange that were passed as flags.
+type fileSource struct {
+	name     string // name of the importer, the flag is name-csv
+	exchange string
+	importer importer
+	files    *fileFlag
+}
+
+// mappingFlag is the flag for the column mapping of generic csv files,
+// the mapping is read when the flag is set.
+type mappingFlag struct {
+	path    string
+	mapping *generic.Mapping
+}
+
+func (f *mappingFlag) String() string {
+	return f.path
+}
+
+func (f *mappingFlag) Set(v string) error {
+	m, err := generic.ReadMapping(v)
+	if err != nil {
+		return err
+	}
+
+	f.path, f.mapping = v, m
+
+	return nil
+}
+
+func (f *mappingFlag) exchange() string {
+	if f.mapping == nil {
+		return generic.DefaultExchangeName
+	}
+
+	return f.mapping.ExchangeName()
+}
+
+// exchangeFiles contains the file flags per exchange.
+type exchangeFiles struct {
+	bitcoinCore    fileFlag
+	bitfinex       fileFlag
+	bitpanda       fileFlag
+	bitstamp       fileFlag
+	coinbase       fileFlag
+	coinbasePro    fileFlag
+	coinTracking   fileFlag
+	electrum       fileFlag
+	etherscan      fileFlag
+	gemini         fileFlag
+	generic        fileFlag
+	genericMapping mappingFlag
+	koinly         fileFlag
+	kraken         fileFlag
+	kucoin         fileFlag
+	// mapped are the generic csv files of accounts with their own
+	// mapping
+	mapped []*fileSource
+}
+
+func (e *exchangeFiles) register(fs *flag.FlagSet, usageSuffix string) {
+	fs.Var(&e.bitcoinCore, "bitcoin-core-csv", "path, glob or directory of bitcoin core wallet csv files with the fields of listtransactions, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.bitfinex, "bitfinex-csv", "path, glob or directory of bitfinex trades csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.bitpanda, "bitpanda-csv", "path, glob or directory of bitpanda trade history csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.bitstamp, "bitstamp-csv", "path, glob or directory of bitstamp transactions csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.coinbase, "coinbase-csv", "path, glob or directory of coinbase taxhistory or transaction history csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.coinbasePro, "coinbase-pro-csv", "path, glob or directory of coinbase pro or advanced trade fills csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.coinTracking, "cointracking-csv", "path, glob or directory of cointracking trade list csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.electrum, "electrum-csv", "path, glob or directory of electrum wallet history csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.etherscan, "etherscan-csv", "path, glob or directory of etherscan transactions, internal transactions or erc-20 token transfers csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.gemini, "gemini-csv", "path, glob or directory of gemini transaction history csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.generic, "generic-csv", "path, glob or directory of csv files that are imported according to -generic-mapping, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.genericMapping, "generic-mapping", "path to a json file that describes the columns of the -generic-csv files")
+	fs.Var(&e.koinly, "koinly-csv", "path, glob or directory of koinly universal format csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.kraken, "kraken-csv", "path, glob or directory of kraken trades csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+	fs.Var(&e.kucoin, "kucoin-csv", "path, glob or directory of kucoin trade history csv files, can be passed multiple times, format: [wallet=]path"+usageSuffix)
+}
+
+func (e *exchangeFiles) sources() []*fileSource {
+	res := []*fileSource{
+		{name: "bitcoin-core", exchange: bitcoincore.ExchangeName, importer: &bitcoincore.Import{}, files: &e.bitcoinCore},
+		{name: "bitfinex", exchange: bitfinex.ExchangeName, importer: &bitfinex.Import{}, files: &e.bitfinex},
+		{name: "bitpanda", exchange: bitpanda.ExchangeName, importer: &bitpanda.Import{}, files: &e.bitpanda},
+		{name: "bitstamp", exchange: bitstamp.ExchangeName, importer: &bitstamp.Import{}, files: &e.bitstamp},
+		{name: "coinbase", exchange: coinbase.ExchangeName, importer: &coinbase.Import{}, files: &e.coinbase},
+		{name: "coinbase-pro", exchange: coinbasepro.ExchangeName, importer: &coinbasepro.Import{}, files: &e.coinbasePro},
+		{name: "cointracking", exchange: cointracking.ExchangeName, importer: &cointracking.Import{}, files: &e.coinTracking},
+		{name: "electrum", exchange: electrum.ExchangeName, importer: &electrum.Import{}, files: &e.electrum},
+		{name: "etherscan", exchange: etherscan.ExchangeName, importer: &etherscan.Import{}, files: &e.etherscan},
+		{name: "gemini", exchange: gemini.ExchangeName, importer: &gemini.Import{}, files: &e.gemini},
+		{name: "koinly", exchange: koinly.ExchangeName, importer: &koinly.Import{}, files: &e.koinly},
+		{name: "kraken", exchange: kraken.ExchangeName, importer: &kraken.Import{}, files: &e.kraken},
+		{name: "kucoin", exchange: kucoin.ExchangeName, importer: &kucoin.Import{}, files: &e.kucoin},
+		{name: "generic", exchange: e.genericMapping.exchange(), importer: &generic.Import{Mapping: e.genericMapping.mapping}, files: &e.generic},
+	}
+
+	return append(res, e.mapped...)
+}
+
+// add adds the files of an account, its importer is the name of an
+// importer, e.g. coinbase-pro.
+func (e *exchangeFiles) add(a *config.Account) error {
+	var files fileFlag
+	for _, p := range a.Files {
+		files = append(files, fileArg{wallet: a.Name, pattern: p})
+	}
+
+	if strings.EqualFold(a.Importer, "generic") && len(a.Mapping) != 0 {
+		m, err := generic.ReadMapping(a.Mapping)
+		if err != nil {
+			return err
+		}
+
+		e.mapped = append(e.mapped, &fileSource{
+			name:     "generic",
+			exchange: m.ExchangeName(),
+			importer: &generic.Import{Mapping: m},
+			files:    &files,
+		})
+
+		return nil
+	}
+
+	for _, src := range e.sources() {
+		if !strings.EqualFold(src.name, a.Importer) {
+			continue
+		}
+
+		*src.files = append(*src.files, files...)
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported importer %q", a.Importer)
+}
+
+func (e *exchangeFiles) empty() bool {
+	for _, src := range e.sources() {
+		if len(*src.files) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// read calls fn with the transactions of every passed file. The wallet of
+// the transactions is set to the tag of the file, if it has one.
+func (e *exchangeFiles) read(fn func(src *fileSource, file *taggedFile, records []*transaction.Tx) error) error {
+	for _, src := range e.sources() {
+		files, err := src.files.files()
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			log.Infof("reading %s", file.path)
+			records, err := src.importer.FromCSV(file.path)
+			if err != nil {
+				return fmt.Errorf("reading %s failed: %w", file.path, err)
+			}
+
+			if len(file.wallet) != 0 {
+				for _, rec := range records {
+					rec.Wallet = file.wallet
+				}
+			}
+
+			err = fn(src, file, records)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package bitcoincore imports the transactions of a Bitcoin Core wallet
+// from CSV files with the fields of the listtransactions RPC.
+package bitcoincore
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Bitcoin Core"
+
+type Import struct{}
+
+/* csv format, every output of a transaction is a row, the fee of a
+transaction is repeated in each of its send rows:
+address,category,amount,label,vout,fee,confirmations,blockhash,blocktime,txid,time,timereceived
+bc1qexample,receive,0.01000000,from exchange,0,,1200,0000...,1609840800,4a5e1e4b...,1609840800,1609840800
+bc1qother,send,-0.00500000,,1,-0.00010000,900,0000...,1612204200,9b0fc921...,1612204200,1612204200
+*/
+
+var requiredColumns = []string{
+	"category",
+	"amount",
+	"txid",
+	"time",
+}
+
+// wtx is a wallet transaction, the sum of its rows.
+type wtx struct {
+	id        string
+	ts        time.Time
+	amount    math.Decimal // change of the balance without the fee
+	fee       math.Decimal
+	generated bool
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var order []*wtx
+	byID := map[string]*wtx{}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	cols := csvfile.ParseHeader(rec)
+	if err := cols.Require(requiredColumns...); err != nil {
+		return nil, fmt.Errorf("import-bitcoincore: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		category := cols.Get(rec, "category")
+		switch category {
+		case "send", "receive", "generate":
+		case "immature", "orphan":
+			// mined coins that are not spendable yet or never
+			// will be
+			log.Debugf("import-bitcoincore: skipping %s transaction: %v", category, rec)
+			continue
+		default:
+			return nil, fmt.Errorf("import-bitcoincore: unsupported category %q: %v", category, rec)
+		}
+
+		id := cols.Get(rec, "txid")
+
+		t, exist := byID[id]
+		if !exist {
+			sec, err := strconv.ParseInt(cols.Get(rec, "time"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("import-bitcoincore: parsing time failed: %s: %v", err, rec)
+			}
+
+			t = &wtx{id: id, ts: time.Unix(sec, 0).UTC()}
+			byID[id] = t
+			order = append(order, t)
+		}
+
+		amount, err := math.ParseDecimal(cols.Get(rec, "amount"))
+		if err != nil {
+			return nil, fmt.Errorf("import-bitcoincore: %s: %v", err, rec)
+		}
+		t.amount = t.amount.Add(amount)
+		t.generated = t.generated || category == "generate"
+
+		if v := cols.Get(rec, "fee"); len(v) != 0 && category == "send" {
+			fee, err := math.ParseDecimal(v)
+			if err != nil {
+				return nil, fmt.Errorf("import-bitcoincore: %s: %v", err, rec)
+			}
+			t.fee = fee.Abs()
+		}
+	}
+
+	var results []*transaction.Tx
+	for _, t := range order {
+		results = append(results, t.tx())
+	}
+
+	return results, nil
+}
+
+func (t *wtx) tx() *transaction.Tx {
+	tx := transaction.Tx{
+		ID:        t.id,
+		Exchange:  ExchangeName,
+		Timestamp: t.ts,
+		Type:      transaction.Deposit,
+		Currency:  transaction.BTC,
+		Quantity:  t.amount,
+	}
+
+	if t.generated {
+		log.Warnf("import-bitcoincore: mined %s BTC in %s are valued with 0€, set the price with an override", t.amount, t.id)
+		tx.Type = transaction.Income
+		tx.PayCurrency = transaction.EUR
+	}
+
+	// payments to own addresses have an amount of 0, only the fee is
+	// paid
+	if t.amount.Sign() < 0 || !t.fee.IsZero() {
+		tx.Type = transaction.Withdrawal
+		tx.Quantity = t.amount.Abs()
+		tx.Fees = t.fee
+		tx.FeeCurrency = transaction.BTC
+	}
+
+	return &tx
+}
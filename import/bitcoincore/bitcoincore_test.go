@@ -0,0 +1,39 @@
+package bitcoincore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/transactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rows of a transaction are summed up, immature coins are
+	// skipped
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+			Type:      transaction.Deposit, Currency: transaction.BTC, Quantity: "0.01",
+		},
+		// the fee is repeated in both send rows
+		{Type: transaction.Withdrawal, Currency: transaction.BTC, Quantity: "0.005", Fees: "0.0001", FeeCurrency: transaction.BTC},
+		// a payment to an own address only pays the fee
+		{Type: transaction.Withdrawal, Currency: transaction.BTC, Quantity: "0", Fees: "0.0001", FeeCurrency: transaction.BTC},
+		{Type: transaction.Income, Currency: transaction.BTC, Quantity: "0.5", PayCurrency: transaction.EUR},
+	})
+
+	ids := []string{"ccc1", "ddd2", "eee3", "fff4"}
+	for i, tx := range res {
+		if tx.ID != ids[i] {
+			t.Errorf("transaction %d: got ID %q, want %q", i, tx.ID, ids[i])
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package bitfinex imports the trades CSV exports of Bitfinex.
+package bitfinex
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Bitfinex"
+
+type Import struct{}
+
+/* csv format:
+#,PAIR,AMOUNT,PRICE,FEE,FEE PERC,FEE CURRENCY,DATE,ORDER ID
+123456,BTC/USD,0.01,30000,-0.06,0.20%,USD,21-01-05 10:00:00,9876
+123457,tETHBTC,-0.5,0.04,-0.00004,0.20%,BTC,21-01-06 11:00:00,9877
+*/
+
+var header = []string{
+	"#",
+	"PAIR",
+	"AMOUNT",
+	"PRICE",
+	"FEE",
+	"FEE PERC",
+	"FEE CURRENCY",
+	"DATE",
+	"ORDER ID",
+}
+
+// currencies maps the Bitfinex symbols that differ from the common ones.
+var currencies = map[string]string{
+	"DSH": "DASH",
+	"IOT": "IOTA",
+	"UST": "USDT",
+}
+
+var timeFormats = []string{
+	"06-01-02 15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.000",
+}
+
+func parseCurrency(v string) (transaction.Currency, error) {
+	sym := strings.ToUpper(v)
+	if c, exist := currencies[sym]; exist {
+		sym = c
+	}
+
+	currency, err := transaction.RegisterCurrency(sym)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q failed: %s", v, err)
+	}
+
+	return currency, nil
+}
+
+// parsePair parses a trading pair, e.g. BTC/USD, tBTCUSD or
+// tTESTBTC:TESTUSD.
+func parsePair(v string) (base, quote transaction.Currency, err error) {
+	var fields []string
+
+	str := strings.TrimPrefix(v, "t")
+	switch {
+	case strings.Contains(str, "/"):
+		fields = strings.Split(str, "/")
+	case strings.Contains(str, ":"):
+		fields = strings.Split(str, ":")
+	case len(str) == 6:
+		fields = []string{str[:3], str[3:]}
+	}
+
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("parsing pair %q failed: unsupported format", v)
+	}
+
+	base, err = parseCurrency(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	quote, err = parseCurrency(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return base, quote, nil
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = len(header)
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csvfile.CheckHeader(rec, header); err != nil {
+		return nil, fmt.Errorf("import-bitfinex: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := parseTrade(rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-bitfinex: %s: %v", err, rec)
+		}
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+func parseTrade(rec []string) (*transaction.Tx, error) {
+	base, quote, err := parsePair(rec[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// the amount is negative for sells
+	amount, err := math.ParseDecimal(rec[2])
+	if err != nil {
+		return nil, err
+	}
+
+	txType := transaction.Buy
+	if amount.Sign() < 0 {
+		txType = transaction.Sell
+	}
+
+	price, err := math.ParseDecimal(rec[3])
+	if err != nil {
+		return nil, err
+	}
+
+	// fees are negative
+	fee, err := math.ParseDecimal(rec[4])
+	if err != nil {
+		return nil, err
+	}
+
+	feeCurrency, err := parseCurrency(rec[6])
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := csvfile.ParseTime(rec[7], time.UTC, timeFormats...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[7], err)
+	}
+
+	return &transaction.Tx{
+		ID:          rec[0],
+		Exchange:    ExchangeName,
+		Timestamp:   ts,
+		Type:        txType,
+		PayCurrency: quote,
+		Currency:    base,
+		Quantity:    amount.Abs(),
+		SpotPrice:   price,
+		Fees:        fee.Abs(),
+		FeeCurrency: feeCurrency,
+	}, nil
+}
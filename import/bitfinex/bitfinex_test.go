@@ -0,0 +1,50 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/trades.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "0.00002", FeeCurrency: transaction.BTC,
+		},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.005", PayCurrency: transaction.EUR, SpotPrice: "40000", Fees: "0.4", FeeCurrency: transaction.EUR},
+		{Type: transaction.Sell, Currency: transaction.ETH, Quantity: "0.5", PayCurrency: transaction.BTC, SpotPrice: "0.04", Fees: "0.00004", FeeCurrency: transaction.BTC},
+		// Bitfinex symbols are mapped to the common ones
+		{
+			Timestamp: time.Date(2021, 3, 1, 8, 30, 0, 250*int(time.Millisecond), time.UTC),
+			Type:      transaction.Buy, Currency: importtest.Currency(t, "DASH"), Quantity: "2", PayCurrency: importtest.Currency(t, "USDT"), SpotPrice: "50.5", Fees: "0.2", FeeCurrency: importtest.Currency(t, "USDT"),
+		},
+	})
+}
+
+func TestParsePair(t *testing.T) {
+	for _, pair := range []string{"BTC/EUR", "tBTCEUR", "tBTC:EUR"} {
+		base, quote, err := parsePair(pair)
+		if err != nil {
+			t.Errorf("parsing %q failed: %s", pair, err)
+			continue
+		}
+
+		if base != transaction.BTC || quote != transaction.EUR {
+			t.Errorf("parsing %q returned %s/%s, want BTC/EUR", pair, base, quote)
+		}
+	}
+
+	if _, _, err := parsePair("tBTCEURO"); err == nil {
+		t.Error("parsing a pair of unknown format succeeded")
+	}
+}
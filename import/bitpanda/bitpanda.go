@@ -0,0 +1,268 @@
+// Package bitpanda imports the trade history CSV exports of Bitpanda.
+package bitpanda
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Bitpanda"
+
+type Import struct{}
+
+/* csv format, the files start with lines of text before the header:
+"Disclaimer: All data is without guarantee, errors and changes are reserved."
+"Account holder","Jane Doe"
+Transaction ID,Timestamp,Transaction Type,In/Out,Amount Fiat,Fiat,Amount Asset,Asset,Asset market price,Asset market price currency,Asset class,Product ID,Fee,Fee asset,Spread,Spread Currency
+T1,2021-01-05T10:00:00+01:00,deposit,incoming,1000.00,EUR,-,EUR,-,-,Fiat,-,-,-,-,-
+T2,2021-01-05T10:05:00+01:00,buy,outgoing,500.00,EUR,0.01650000,BTC,30000.00,EUR,Cryptocurrency,1,0.10000000,BEST,-,-
+T3,2021-02-01T00:00:00+01:00,transfer,incoming,0.40,EUR,1.20000000,BEST,0.33,EUR,Cryptocurrency,33,-,-,-,-
+*/
+
+// columns are the columns of an export.
+type columns struct {
+	csvfile.Columns
+}
+
+// get returns the value of a column, "-" is returned as empty value.
+func (c columns) get(rec []string, name string) string {
+	v := c.Get(rec, name)
+	if v == "-" {
+		return ""
+	}
+
+	return v
+}
+
+var requiredColumns = []string{
+	"Transaction ID",
+	"Timestamp",
+	"Transaction Type",
+	"In/Out",
+	"Amount Fiat",
+	"Fiat",
+	"Amount Asset",
+	"Asset",
+	"Asset market price",
+	"Asset market price currency",
+	"Asset class",
+}
+
+// header returns the columns of rec if it is the header row.
+func header(rec []string) (columns, bool) {
+	cols := columns{csvfile.ParseHeader(rec)}
+	if cols.Require(requiredColumns...) != nil {
+		return columns{}, false
+	}
+
+	return cols, true
+}
+
+// transferTypes maps the direction of transfers to the transaction type.
+// Incoming transfers are rewards, e.g. Bitpanda Best rewards and staking
+// rewards, outgoing transfers move assets to other Bitpanda products.
+var transferTypes = map[string]transaction.Type{
+	"incoming": transaction.Income,
+	"outgoing": transaction.Withdrawal,
+}
+
+// internalTypes are transfers between the wallets of the account, they do
+// not change the holdings.
+var internalTypes = map[string]bool{
+	"transfer(stake)":   true,
+	"transfer(unstake)": true,
+}
+
+const cryptoAssetClass = "cryptocurrency"
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	// the lines before the header have a different number of fields
+	csvReader.FieldsPerRecord = -1
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var cols columns
+	for i, rec := range records {
+		var ok bool
+
+		cols, ok = header(rec)
+		if ok {
+			records = records[i+1:]
+			break
+		}
+	}
+
+	if cols.Columns == nil {
+		return nil, errors.New("import-bitpanda: header row not found")
+	}
+
+	for _, rec := range records {
+		tx, err := parseRow(cols, rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-bitpanda: %s: %v", err, rec)
+		}
+
+		if tx != nil {
+			results = append(results, tx)
+		}
+	}
+
+	return results, nil
+}
+
+func parseDecimal(v string) (math.Decimal, error) {
+	if len(v) == 0 {
+		return math.Decimal{}, nil
+	}
+
+	return math.ParseDecimal(v)
+}
+
+func parseCurrency(v string) (transaction.Currency, error) {
+	currency, err := transaction.RegisterCurrency(v)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q failed: %s", v, err)
+	}
+
+	return currency, nil
+}
+
+// parseRow returns the transaction of a row, nil is returned for rows
+// that do not affect the holdings of cryptocurrencies.
+func parseRow(cols columns, rec []string) (*transaction.Tx, error) {
+	var txType transaction.Type
+
+	kind := strings.ToLower(cols.get(rec, "Transaction Type"))
+	direction := strings.ToLower(cols.get(rec, "In/Out"))
+
+	switch kind {
+	case "buy":
+		txType = transaction.Buy
+	case "sell":
+		txType = transaction.Sell
+	case "deposit":
+		txType = transaction.Deposit
+	case "withdrawal":
+		txType = transaction.Withdrawal
+	case "transfer":
+		var exist bool
+		txType, exist = transferTypes[direction]
+		if !exist {
+			return nil, fmt.Errorf("unsupported transfer direction %q", direction)
+		}
+	default:
+		if internalTypes[kind] {
+			log.Debugf("import-bitpanda: skipping internal %s: %v", kind, rec)
+			return nil, nil
+		}
+
+		log.Infof("import-bitpanda: skipping unsupported transaction type %q: %v", kind, rec)
+		return nil, nil
+	}
+
+	assetClass := strings.ToLower(cols.get(rec, "Asset class"))
+	if assetClass != cryptoAssetClass {
+		if assetClass == "fiat" {
+			log.Debugf("import-bitpanda: skipping fiat %s: %v", txType, rec)
+		} else {
+			log.Warnf("import-bitpanda: skipping %s of unsupported asset class %q: %v", txType, assetClass, rec)
+		}
+
+		return nil, nil
+	}
+
+	id := cols.get(rec, "Transaction ID")
+	if len(id) == 0 {
+		return nil, errors.New("transaction id is empty")
+	}
+
+	ts, err := time.Parse(time.RFC3339, cols.get(rec, "Timestamp"))
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := parseCurrency(cols.get(rec, "Asset"))
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := parseDecimal(cols.get(rec, "Amount Asset"))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := transaction.Tx{
+		ID:        id,
+		Exchange:  ExchangeName,
+		Timestamp: ts,
+		Type:      txType,
+		Currency:  currency,
+		Quantity:  quantity,
+	}
+
+	if feeAsset := cols.get(rec, "Fee asset"); len(feeAsset) != 0 {
+		tx.Fees, err = parseDecimal(cols.get(rec, "Fee"))
+		if err != nil {
+			return nil, err
+		}
+
+		tx.FeeCurrency, err = parseCurrency(feeAsset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch txType {
+	case transaction.Buy, transaction.Sell:
+		// the fiat amount includes the spread, the price is derived from
+		// it instead of the market price
+		amount, err := parseDecimal(cols.get(rec, "Amount Fiat"))
+		if err != nil {
+			return nil, err
+		}
+
+		tx.PayCurrency, err = parseCurrency(cols.get(rec, "Fiat"))
+		if err != nil {
+			return nil, err
+		}
+
+		if quantity.IsZero() {
+			return nil, errors.New("asset amount is 0")
+		}
+
+		tx.SpotPrice = amount.Quo(quantity, math.DivScale, math.RoundHalfEven).Normalize()
+	case transaction.Income:
+		tx.SpotPrice, err = parseDecimal(cols.get(rec, "Asset market price"))
+		if err != nil {
+			return nil, err
+		}
+
+		tx.PayCurrency, err = parseCurrency(cols.get(rec, "Asset market price currency"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &tx, nil
+}
@@ -0,0 +1,42 @@
+package bitpanda
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/trade-history.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	best := importtest.Currency(t, "BEST")
+
+	// fiat deposits, staking transfers and stocks are skipped
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 9, 5, 0, 0, time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.0165", PayCurrency: transaction.EUR, SpotPrice: "30000",
+		},
+		// incoming transfers are rewards valued at the market price
+		{Type: transaction.Income, Currency: best, Quantity: "1.2", PayCurrency: transaction.EUR, SpotPrice: "0.33"},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "60000", Fees: "0.5", FeeCurrency: best},
+		{Type: transaction.Withdrawal, Currency: transaction.ETH, Quantity: "0.05", Fees: "0.001", FeeCurrency: transaction.ETH},
+	})
+
+	if res[0].ID != "T2" || res[0].Exchange != ExchangeName {
+		t.Errorf("got ID %q at %q, want T2 at %s", res[0].ID, res[0].Exchange, ExchangeName)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	if _, ok := header([]string{"Account holder", "Jane Doe"}); ok {
+		t.Error("a line before the header is detected as header")
+	}
+}
@@ -0,0 +1,219 @@
+// Package bitstamp imports the transactions CSV exports of Bitstamp.
+package bitstamp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/import/rowid"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Bitstamp"
+
+type Import struct{}
+
+/* csv format:
+Type,Datetime,Account,Amount,Value,Rate,Fee,Sub Type
+Deposit,"Jan. 05, 2021, 09:00 AM",Main Account,1000.00 EUR,,,,
+Market,"Jan. 05, 2021, 10:00 AM",Main Account,0.05000000 BTC,1500.00 EUR,30000.00 EUR,3.75000 EUR,Buy
+Withdrawal,"Feb. 01, 2021, 08:30 PM",Main Account,0.01000000 BTC,,,0.00050000 BTC,
+*/
+
+var header = []string{
+	"Type",
+	"Datetime",
+	"Account",
+	"Amount",
+	"Value",
+	"Rate",
+	"Fee",
+	"Sub Type",
+}
+
+// timeFormats of the Datetime column, May is not abbreviated and has no
+// dot
+var timeFormats = []string{
+	"Jan. 02, 2006, 03:04 PM",
+	"Jan 02, 2006, 03:04 PM",
+}
+
+// mainAccount is the name of the default account, it is not recorded as
+// wallet.
+const mainAccount = "Main Account"
+
+// parseAmount parses a value with a currency suffix, e.g. "0.5 BTC".
+func parseAmount(v string) (math.Decimal, transaction.Currency, error) {
+	fields := strings.Fields(v)
+	if len(fields) != 2 {
+		return math.Decimal{}, 0, fmt.Errorf("parsing %q failed: expected format: AMOUNT CURRENCY", v)
+	}
+
+	amount, err := math.ParseDecimal(fields[0])
+	if err != nil {
+		return math.Decimal{}, 0, err
+	}
+
+	currency, err := transaction.RegisterCurrency(fields[1])
+	if err != nil {
+		return math.Decimal{}, 0, fmt.Errorf("parsing %q failed: %s", fields[1], err)
+	}
+
+	return amount, currency, nil
+}
+
+// parseRate parses the Rate column, older exports contain it without
+// currency.
+func parseRate(v string) (math.Decimal, error) {
+	fields := strings.Fields(v)
+	if len(fields) == 0 {
+		return math.Decimal{}, fmt.Errorf("rate is empty")
+	}
+
+	return math.ParseDecimal(fields[0])
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+	var ids rowid.Generator
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = len(header)
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csvfile.CheckHeader(rec, header); err != nil {
+		return nil, fmt.Errorf("import-bitstamp: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := parseRow(rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-bitstamp: %s: %v", err, rec)
+		}
+
+		if tx == nil {
+			continue
+		}
+
+		// rows have no ID, it is derived from the content
+		tx.ID = ids.ID(rec)
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+// parseRow returns the transaction of a row, nil is returned for rows
+// that do not affect the holdings of cryptocurrencies.
+func parseRow(rec []string) (*transaction.Tx, error) {
+	var txType transaction.Type
+
+	switch strings.ToLower(rec[0]) {
+	case "market":
+		switch strings.ToLower(rec[7]) {
+		case "buy":
+			txType = transaction.Buy
+		case "sell":
+			txType = transaction.Sell
+		default:
+			return nil, fmt.Errorf("unsupported sub type %q", rec[7])
+		}
+	case "deposit":
+		txType = transaction.Deposit
+	case "withdrawal":
+		txType = transaction.Withdrawal
+	default:
+		log.Infof("import-bitstamp: skipping unsupported transaction type %q: %v", rec[0], rec)
+		return nil, nil
+	}
+
+	ts, err := csvfile.ParseTime(rec[1], time.UTC, timeFormats...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[1], err)
+	}
+
+	quantity, currency, err := parseAmount(rec[3])
+	if err != nil {
+		return nil, err
+	}
+
+	var fee math.Decimal
+	var feeCurrency transaction.Currency
+	if len(rec[6]) != 0 {
+		fee, feeCurrency, err = parseAmount(rec[6])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx := transaction.Tx{
+		Exchange:    ExchangeName,
+		Wallet:      account(rec[2]),
+		Timestamp:   ts,
+		Type:        txType,
+		Currency:    currency,
+		Quantity:    quantity,
+		Fees:        fee,
+		FeeCurrency: feeCurrency,
+	}
+
+	if txType == transaction.Deposit || txType == transaction.Withdrawal {
+		if currency.IsFiat() {
+			log.Debugf("import-bitstamp: skipping fiat %s: %v", txType, rec)
+			return nil, nil
+		}
+
+		return &tx, nil
+	}
+
+	_, payCurrency, err := parseAmount(rec[4])
+	if err != nil {
+		return nil, err
+	}
+
+	spotPrice, err := parseRate(rec[5])
+	if err != nil {
+		return nil, err
+	}
+
+	tx.PayCurrency = payCurrency
+	tx.SpotPrice = spotPrice
+
+	return &tx, nil
+}
+
+// account returns the wallet name of an account, the main account has
+// none.
+func account(v string) string {
+	if strings.EqualFold(v, mainAccount) {
+		return ""
+	}
+
+	return v
+}
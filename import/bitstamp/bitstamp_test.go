@@ -0,0 +1,50 @@
+package bitstamp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/transactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fiat deposits and sub account transfers are skipped
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.05",
+			PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "3.75", FeeCurrency: transaction.EUR,
+		},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.02", PayCurrency: transaction.USD, SpotPrice: "50000", Fees: "2", FeeCurrency: transaction.USD},
+		{
+			Timestamp: time.Date(2021, 5, 1, 20, 30, 0, 0, time.UTC),
+			Type:      transaction.Withdrawal, Currency: transaction.BTC, Quantity: "0.01", Fees: "0.0005", FeeCurrency: transaction.BTC,
+		},
+		// older exports contain the rate without currency
+		{Type: transaction.Buy, Currency: transaction.ETH, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "2000"},
+	})
+
+	if res[0].Wallet != "" || res[3].Wallet != "Savings" {
+		t.Errorf("wallets are %q and %q, want none for the main account and Savings", res[0].Wallet, res[3].Wallet)
+	}
+}
+
+func TestFromCSVInvalid(t *testing.T) {
+	_, err := parseRow([]string{"Market", "Jan. 05, 2021, 10:00 AM", "Main Account", "0.05 BTC", "1500 EUR", "30000 EUR", "", "Swap"})
+	if err == nil {
+		t.Error("parsing a market row with an unsupported sub type succeeded")
+	}
+
+	_, err = parseRow([]string{"Deposit", "Jan. 05, 2021, 10:00 AM", "Main Account", "0.05", "", "", "", ""})
+	if err == nil {
+		t.Error("parsing an amount without currency succeeded")
+	}
+}
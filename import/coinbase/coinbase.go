@@ -1,14 +1,19 @@
+// Package coinbase imports the CSV exports of Coinbase.
+//
+// Supported are the taxhistory export with dates without time of day and
+// the transaction history exports with timestamps, that are available since
+// 2021.
 package coinbase
 
 import (
 	"encoding/csv"
-	"io"
-	"log"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/twinj/uuid"
-
+	"github.com/fho/cryptotax/import/rowid"
+	"github.com/fho/cryptotax/log"
 	"github.com/fho/cryptotax/math"
 	"github.com/fho/cryptotax/transaction"
 )
@@ -18,9 +23,6 @@ const ExchangeName = "Coinbase"
 type Import struct{}
 
 func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
-	const recFields = 8
-	var results []*transaction.Tx
-
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -29,68 +31,94 @@ func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
 	defer f.Close()
 
 	csvReader := csv.NewReader(f)
+	// the transaction history exports start with lines of text
+	csvReader.FieldsPerRecord = -1
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
 
-	for {
-		/* csv format:
-		Timestamp,Transaction Type,Asset,Quantity Transacted,EUR Spot Price at Transaction,EUR quantity Transacted (Inclusive of Coinbase Fees),Address,Notes
-		*/
-		rec, err := csvReader.Read()
-		if err == io.EOF {
-			break
+	for i, rec := range records {
+		if cols, ok := historyHeader(rec); ok {
+			return fromHistory(cols, records[i+1:])
 		}
+	}
+
+	return fromTaxHistory(records)
+}
+
+func fromTaxHistory(records [][]string) ([]*transaction.Tx, error) {
+	const recFields = 8
+	var results []*transaction.Tx
+	var ids rowid.Generator
+
+	/* csv format:
+	Timestamp,Transaction Type,Asset,Quantity Transacted,EUR Spot Price at Transaction,EUR quantity Transacted (Inclusive of Coinbase Fees),Address,Notes
+	*/
+	for _, rec := range records {
 		if len(rec) != recFields {
-			log.Printf("import-coinbase: skipping line: %v", rec)
+			log.Debugf("import-coinbase: skipping line: %v", rec)
 			continue
 		}
 
 		ts, err := time.Parse("01/02/2006", rec[0])
 		if err != nil {
-			log.Printf("import-coinbase: skipping line: %v", rec)
+			log.Debugf("import-coinbase: skipping line: %v", rec)
 			continue
 		}
 
-		if rec[1] == "Send" || rec[1] == "Receive" {
-			log.Printf("import coinbase: skipping line: %v", rec)
-			continue
+		txCur, err := transaction.RegisterCurrency(rec[2])
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing %q failed: %s", rec[2], err)
 		}
 
-		txType, err := transaction.NewType(rec[1])
+		quantity, err := math.ParseDecimal(rec[3])
 		if err != nil {
-			log.Fatalf("import-coinbase: parsing %q failed: %s", rec[1], err)
+			return nil, fmt.Errorf("import-coinbase: converting %q to decimal failed: %s", rec[3], err)
 		}
 
-		txCur, err := transaction.NewCurrency(rec[2])
-		if err != nil {
-			log.Fatalf("import-coinbase: parsing %q failed: %s", rec[2], err)
+		if strings.EqualFold(rec[1], convertType) {
+			tx, err := convertTx(rec[7], txCur, quantity)
+			if err != nil {
+				return nil, fmt.Errorf("import-coinbase: %s: %v", err, rec)
+			}
+
+			tx.ID = ids.ID(rec)
+			tx.Timestamp = ts
+
+			results = append(results, tx)
+			continue
 		}
 
-		quantity, success := math.NewFloat().SetString(rec[3])
-		if !success {
-			log.Fatalf("import-coinbase: converting %q to big float failed", rec[3])
+		// the taxhistory export has the same transaction types as the
+		// transaction history, it does not contain network fees
+		txType, exist := historyTypes[strings.ToLower(rec[1])]
+		if !exist {
+			return nil, fmt.Errorf("import-coinbase: parsing %q failed: %s", rec[1], transaction.ErrUndefinedType)
 		}
 
-		spotPrice, success := math.NewFloat().SetString(rec[4])
-		if !success {
-			log.Fatalf("import-coinbase: converting %q to big float failed", rec[4])
+		spotPrice, err := math.ParseDecimal(rec[4])
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: converting %q to decimal failed: %s", rec[4], err)
 		}
 
-		totalPriceWFees, success := math.NewFloat().SetString(rec[5])
-		if !success {
-			log.Fatalf("import-coinbase: converting %q to big float failed", rec[5])
+		totalPriceWFees, err := math.ParseDecimal(rec[5])
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: converting %q to decimal failed: %s", rec[5], err)
 		}
 
-		var totalPrice = math.NewFloat()
-		var fees = math.NewFloat()
-		totalPrice.Mul(spotPrice, quantity)
+		var fees math.Decimal
+		totalPrice := spotPrice.Mul(quantity)
 
 		if txType == transaction.Buy {
-			fees.Sub(totalPriceWFees, totalPrice)
+			fees = totalPriceWFees.Sub(totalPrice)
 		} else if txType == transaction.Sell {
-			fees.Sub(totalPrice, totalPriceWFees)
+			fees = totalPrice.Sub(totalPriceWFees)
 		}
 
 		txRec := transaction.Tx{
-			ID:          uuid.NewV4().String(),
+			ID:          ids.ID(rec),
 			Exchange:    ExchangeName,
 			Timestamp:   ts,
 			Type:        txType,
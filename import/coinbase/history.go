@@ -0,0 +1,290 @@
+package coinbase
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/import/rowid"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+/* transaction history csv formats, the files start with lines of text before
+the header:
+
+2021 - 2022:
+Timestamp,Transaction Type,Asset,Quantity Transacted,Spot Price Currency,Spot Price at Transaction,Subtotal,Total (inclusive of fees),Fees,Notes
+2021-04-02T14:00:00Z,Buy,BTC,0.01,EUR,50000.00,500.00,507.50,7.50,Bought 0.01 BTC for €507.50 EUR
+
+since 2023:
+ID,Timestamp,Transaction Type,Asset,Quantity Transacted,Price Currency,Price at Transaction,Subtotal,Total (inclusive of fees and/or spread),Fees and/or Spread,Notes
+65a1b2c3,2024-01-05 10:00:00 UTC,Buy,BTC,0.001,EUR,€40000.00,€40.00,€41.99,€1.99,Bought 0.001 BTC for €41.99 EUR
+*/
+
+// historyHeader returns the columns of rec if it is the header of a
+// transaction history export.
+func historyHeader(rec []string) (csvfile.Columns, bool) {
+	cols := csvfile.ParseHeader(rec)
+
+	_, hasType := cols["Transaction Type"]
+	_, hasPriceCurrency := cols.Index("Spot Price Currency", "Price Currency")
+
+	return cols, hasType && hasPriceCurrency
+}
+
+var historyTypes = map[string]transaction.Type{
+	"buy":                 transaction.Buy,
+	"advanced trade buy":  transaction.Buy,
+	"sell":                transaction.Sell,
+	"advanced trade sell": transaction.Sell,
+	"rewards income":      transaction.Income,
+	"staking income":      transaction.Income,
+	"inflation reward":    transaction.Income,
+	"learning reward":     transaction.Income,
+	"coinbase earn":       transaction.Income,
+	"send":                transaction.Withdrawal,
+	"receive":             transaction.Deposit,
+}
+
+// fiatHistoryTypes are transfers of fiat money, they are not relevant
+var fiatHistoryTypes = map[string]bool{
+	"deposit":    true,
+	"withdrawal": true,
+}
+
+var historyTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+}
+
+func parseHistoryTime(v string) (time.Time, error) {
+	var err error
+
+	for _, f := range historyTimeFormats {
+		var ts time.Time
+
+		ts, err = time.Parse(f, v)
+		if err == nil {
+			return ts, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// parseAmount parses a number that can be prefixed with a currency sign
+// and contain thousands separators, e.g. €1,234.50. An empty value is 0.
+func parseAmount(v string) (math.Decimal, error) {
+	str := strings.NewReplacer("€", "", "$", "", "£", "", ",", "").Replace(v)
+	if len(strings.TrimSpace(str)) == 0 {
+		return math.Decimal{}, nil
+	}
+
+	return math.ParseDecimal(str)
+}
+
+const convertType = "convert"
+
+// convertNote matches the notes of Convert transactions, e.g.:
+// Converted 0.5 SOL to 0.001 ETH
+var convertNote = regexp.MustCompile(`(?i)^converted\s+([0-9.,]+)\s+(\S+)\s+to\s+([0-9.,]+)\s+(\S+)`)
+
+// convertTx returns the trade of a Convert transaction. The received
+// currency and quantity are only contained in the notes.
+// The trade is booked as sell of the converted currency that is paid with
+// the received one, this keeps the disposed quantity exact.
+func convertTx(notes string, currency transaction.Currency, quantity math.Decimal) (*transaction.Tx, error) {
+	m := convertNote.FindStringSubmatch(notes)
+	if m == nil {
+		return nil, fmt.Errorf("parsing convert notes %q failed", notes)
+	}
+
+	from, err := transaction.RegisterCurrency(m[2])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", m[2], err)
+	}
+
+	if from != currency {
+		return nil, fmt.Errorf("converted currency %s in notes differs from asset %s", from, currency)
+	}
+
+	to, err := transaction.RegisterCurrency(m[4])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", m[4], err)
+	}
+
+	toQuantity, err := parseAmount(m[3])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", m[3], err)
+	}
+
+	if quantity.IsZero() {
+		quantity, err = parseAmount(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %s", m[1], err)
+		}
+	}
+
+	if quantity.IsZero() {
+		return nil, errors.New("converted quantity is 0")
+	}
+
+	return &transaction.Tx{
+		Exchange:    ExchangeName,
+		Type:        transaction.Sell,
+		PayCurrency: to,
+		Currency:    currency,
+		Quantity:    quantity,
+		// rounded up, a rounding remainder stays in the received lot
+		// instead of missing when it is sold
+		SpotPrice: toQuantity.Quo(quantity, math.DivScale, math.RoundUp).Normalize(),
+	}, nil
+}
+
+func fromHistory(cols csvfile.Columns, records [][]string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+	var ids rowid.Generator
+
+	for _, rec := range records {
+		if len(rec) < len(cols) {
+			log.Debugf("import-coinbase: skipping line: %v", rec)
+			continue
+		}
+
+		typeStr := cols.Get(rec, "Transaction Type")
+		key := strings.ToLower(typeStr)
+
+		if fiatHistoryTypes[key] {
+			log.Debugf("import-coinbase: skipping fiat transfer: %v", rec)
+			continue
+		}
+
+		txType, exist := historyTypes[key]
+		if !exist && key != convertType {
+			return nil, fmt.Errorf("import-coinbase: unsupported transaction type %q: %v", typeStr, rec)
+		}
+
+		ts, err := parseHistoryTime(cols.Get(rec, "Timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing timestamp failed: %s", err)
+		}
+
+		asset := cols.Get(rec, "Asset")
+		currency, err := transaction.RegisterCurrency(asset)
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing %q failed: %s", asset, err)
+		}
+
+		priceCurrencyStr := cols.Get(rec, "Price Currency", "Spot Price Currency")
+		priceCurrency, err := transaction.RegisterCurrency(priceCurrencyStr)
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing %q failed: %s", priceCurrencyStr, err)
+		}
+
+		if priceCurrency != transaction.EUR {
+			return nil, fmt.Errorf("import-coinbase: prices in %s are not supported, the transaction history must be exported in EUR: %v", priceCurrency, rec)
+		}
+
+		quantity, err := parseAmount(cols.Get(rec, "Quantity Transacted"))
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing quantity failed: %s", err)
+		}
+		// newer exports have negative quantities for sells
+		quantity = quantity.Abs()
+
+		spotPrice, err := parseAmount(cols.Get(rec, "Price at Transaction", "Spot Price at Transaction"))
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing price failed: %s", err)
+		}
+
+		total, err := parseAmount(cols.Get(rec, "Total (inclusive of fees and/or spread)", "Total (inclusive of fees)"))
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing total failed: %s", err)
+		}
+		total = total.Abs()
+
+		id := cols.Get(rec, "ID")
+		if len(id) == 0 {
+			id = ids.ID(rec)
+		}
+
+		fees, err := parseAmount(cols.Get(rec, "Fees and/or Spread", "Fees"))
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbase: parsing fees failed: %s", err)
+		}
+
+		if key == convertType {
+			tx, err := convertTx(cols.Get(rec, "Notes"), currency, quantity)
+			if err != nil {
+				return nil, fmt.Errorf("import-coinbase: %s: %v", err, rec)
+			}
+
+			subtotal, err := parseAmount(cols.Get(rec, "Subtotal"))
+			if err != nil {
+				return nil, fmt.Errorf("import-coinbase: parsing subtotal failed: %s", err)
+			}
+
+			tx.ID = id
+			tx.Timestamp = ts
+			tx.Fees = fees
+			tx.FeeCurrency = transaction.EUR
+			// the subtotal is the value of the received currency,
+			// the fees are booked separately
+			tx.Value = subtotal.Abs()
+
+			results = append(results, tx)
+			continue
+		}
+
+		tx := transaction.Tx{
+			ID:          id,
+			Exchange:    ExchangeName,
+			Timestamp:   ts,
+			Type:        txType,
+			PayCurrency: transaction.EUR,
+			Currency:    currency,
+			Quantity:    quantity,
+			SpotPrice:   spotPrice,
+		}
+
+		switch txType {
+		case transaction.Buy, transaction.Sell:
+			// the fees include the spread, like in the taxhistory
+			// export
+			totalPrice := spotPrice.Mul(quantity)
+
+			if txType == transaction.Buy {
+				tx.Fees = total.Sub(totalPrice)
+			} else {
+				tx.Fees = totalPrice.Sub(total)
+			}
+
+		case transaction.Withdrawal:
+			// the network fee is exported as € value, it is paid in
+			// the sent currency
+			if fees.Sign() > 0 && spotPrice.Sign() > 0 {
+				tx.Fees = fees.Quo(spotPrice, math.DivScale, math.RoundHalfEven).Normalize()
+				tx.FeeCurrency = currency
+			}
+
+			// the export does not tell if the receiver is an own
+			// wallet
+			log.Warnf("import-coinbase: send %s of %s %s at %s is booked as transfer to an own wallet, if it was sent to someone else change its type to gift-sent or sell with an override",
+				id, quantity, currency, ts.Format(time.RFC3339))
+
+		case transaction.Deposit:
+			log.Warnf("import-coinbase: receive %s of %s %s at %s is booked as transfer from an own wallet, if it was received from someone else change its type to income or gift-received with an override, it is valued at the spot price",
+				id, quantity, currency, ts.Format(time.RFC3339))
+		}
+
+		results = append(results, &tx)
+	}
+
+	return results, nil
+}
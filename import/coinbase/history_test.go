@@ -0,0 +1,73 @@
+package coinbase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromHistory(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/transaction-history-2021.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sol := importtest.Currency(t, "SOL")
+
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 4, 2, 14, 0, 0, 0, time.UTC),
+			Type:      transaction.Buy, Currency: sol, Quantity: "3", PayCurrency: transaction.EUR, SpotPrice: "30", Fees: "2",
+		},
+		// converts are sells paid with the received currency, valued
+		// at the subtotal
+		{Type: transaction.Sell, Currency: sol, Quantity: "2", PayCurrency: transaction.ETH, SpotPrice: "0.015", Fees: "0.5", FeeCurrency: transaction.EUR, Value: "80"},
+		{Type: transaction.Deposit, Currency: transaction.BTC, Quantity: "0.02", PayCurrency: transaction.EUR, SpotPrice: "30000"},
+		// the network fee is paid in the sent currency
+		{Type: transaction.Withdrawal, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "0.00001", FeeCurrency: transaction.BTC},
+		{Type: transaction.Sell, Currency: transaction.ETH, Quantity: "0.03", PayCurrency: transaction.EUR, SpotPrice: "2000", Fees: "1"},
+	})
+}
+
+func TestFromHistorySince2023(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/transaction-history.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sol := importtest.Currency(t, "SOL")
+
+	// the fiat deposit is skipped
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.001", PayCurrency: transaction.EUR, SpotPrice: "40000", Fees: "1.99",
+		},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.0005", PayCurrency: transaction.EUR, SpotPrice: "50000", Fees: "0.2"},
+		{Type: transaction.Income, Currency: sol, Quantity: "0.5", PayCurrency: transaction.EUR, SpotPrice: "100"},
+		{Type: transaction.Sell, Currency: sol, Quantity: "0.5", PayCurrency: transaction.ETH, SpotPrice: "0.002", FeeCurrency: transaction.EUR, Value: "50"},
+	})
+
+	if res[0].ID != "id1" {
+		t.Errorf("ID is %q, want the ID of the export", res[0].ID)
+	}
+}
+
+func TestConvertTx(t *testing.T) {
+	tests := []string{
+		"Converted 0.5 SOL",
+		"Converted 0.5 ETH to 0.001 BTC",
+	}
+
+	for _, notes := range tests {
+		if _, err := convertTx(notes, transaction.LTC, importtest.Decimal(t, "0.5")); err == nil {
+			t.Errorf("parsing convert notes %q of LTC succeeded", notes)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package coinbasepro imports the fills CSV exports of Coinbase Pro and
+// Coinbase Advanced Trade.
+package coinbasepro
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Coinbase Pro"
+
+type Import struct{}
+
+/* csv format:
+portfolio,trade id,product,side,created at,size,size unit,price,fee,total,price/fee/total unit
+default,1234567,BTC-EUR,BUY,2021-01-05T10:00:00.123Z,0.01,BTC,30000.00,1.5,-301.5,EUR
+*/
+
+var header = []string{
+	"portfolio",
+	"trade id",
+	"product",
+	"side",
+	"created at",
+	"size",
+	"size unit",
+	"price",
+	"fee",
+	"total",
+	"price/fee/total unit",
+}
+
+func parseProduct(v string) (base, quote transaction.Currency, err error) {
+	fields := strings.Split(v, "-")
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("parsing product %q failed: expected format BASE-QUOTE", v)
+	}
+
+	base, err = transaction.RegisterCurrency(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing %q failed: %s", fields[0], err)
+	}
+
+	quote, err = transaction.RegisterCurrency(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing %q failed: %s", fields[1], err)
+	}
+
+	return base, quote, nil
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = len(header)
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csvfile.CheckHeader(rec, header); err != nil {
+		return nil, fmt.Errorf("import-coinbasepro: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := parseFill(rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-coinbasepro: %s: %v", err, rec)
+		}
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+func parseFill(rec []string) (*transaction.Tx, error) {
+	base, quote, err := parseProduct(rec[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var txType transaction.Type
+	switch strings.ToUpper(rec[3]) {
+	case "BUY":
+		txType = transaction.Buy
+	case "SELL":
+		txType = transaction.Sell
+	default:
+		return nil, fmt.Errorf("unsupported side %q", rec[3])
+	}
+
+	ts, err := time.Parse(time.RFC3339, rec[4])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[4], err)
+	}
+
+	size, err := math.ParseDecimal(rec[5])
+	if err != nil {
+		return nil, err
+	}
+
+	sizeUnit, err := transaction.RegisterCurrency(rec[6])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[6], err)
+	}
+
+	price, err := math.ParseDecimal(rec[7])
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := math.ParseDecimal(rec[8])
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := transaction.RegisterCurrency(rec[10])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[10], err)
+	}
+
+	if unit != quote {
+		return nil, fmt.Errorf("price unit %s differs from the quote currency %s", unit, quote)
+	}
+
+	quantity := size
+	switch sizeUnit {
+	case base:
+	case quote:
+		if price.IsZero() {
+			return nil, fmt.Errorf("price is 0")
+		}
+		quantity = size.Quo(price, math.DivScale, math.RoundHalfEven).Normalize()
+	default:
+		return nil, fmt.Errorf("size unit %s is neither %s nor %s", sizeUnit, base, quote)
+	}
+
+	return &transaction.Tx{
+		ID:          rec[2] + ":" + rec[1],
+		Exchange:    ExchangeName,
+		Wallet:      portfolio(rec[0]),
+		Timestamp:   ts,
+		Type:        txType,
+		PayCurrency: quote,
+		Currency:    base,
+		Quantity:    quantity,
+		SpotPrice:   price,
+		Fees:        fee,
+		FeeCurrency: unit,
+	}, nil
+}
+
+// portfolio returns the wallet name of a portfolio, the default portfolio
+// has none.
+func portfolio(v string) string {
+	if strings.EqualFold(v, "default") {
+		return ""
+	}
+
+	return v
+}
@@ -0,0 +1,49 @@
+package coinbasepro
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/fills.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 123000000, time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.01",
+			PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "1.5", FeeCurrency: transaction.EUR,
+		},
+		{Type: transaction.Buy, Currency: transaction.ETH, Quantity: "0.1", PayCurrency: transaction.BTC, SpotPrice: "0.05", Fees: "0.00001", FeeCurrency: transaction.BTC},
+		// the size is in the quote currency
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.0075", PayCurrency: transaction.EUR, SpotPrice: "40000", Fees: "1", FeeCurrency: transaction.EUR},
+	})
+
+	if res[0].ID != "BTC-EUR:1" || res[0].Wallet != "" || res[2].Wallet != "trading" {
+		t.Errorf("got ID %q and wallets %q, %q", res[0].ID, res[0].Wallet, res[2].Wallet)
+	}
+}
+
+func TestParseFillInvalid(t *testing.T) {
+	tests := map[string][]string{
+		"unsupported side":   {"default", "1", "BTC-EUR", "HOLD", "2021-01-05T10:00:00Z", "0.01", "BTC", "30000", "1.5", "-301.5", "EUR"},
+		"invalid product":    {"default", "1", "BTCEUR", "BUY", "2021-01-05T10:00:00Z", "0.01", "BTC", "30000", "1.5", "-301.5", "EUR"},
+		"other price unit":   {"default", "1", "BTC-EUR", "BUY", "2021-01-05T10:00:00Z", "0.01", "BTC", "30000", "1.5", "-301.5", "USD"},
+		"other size unit":    {"default", "1", "BTC-EUR", "BUY", "2021-01-05T10:00:00Z", "0.01", "ETH", "30000", "1.5", "-301.5", "EUR"},
+		"zero price in size": {"default", "1", "BTC-EUR", "BUY", "2021-01-05T10:00:00Z", "300", "EUR", "0", "1.5", "-301.5", "EUR"},
+	}
+
+	for name, rec := range tests {
+		if _, err := parseFill(rec); err == nil {
+			t.Errorf("parsing a fill with %s succeeded", name)
+		}
+	}
+}
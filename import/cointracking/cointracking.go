@@ -0,0 +1,348 @@
+// Package cointracking imports the trade list CSV exports of CoinTracking.
+package cointracking
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/import/rowid"
+	"github.com/fho/cryptotax/import/universal"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "CoinTracking"
+
+type Import struct{}
+
+/* csv formats, the currency columns of the trade list export are named
+"Cur." and refer to the preceding column:
+
+"Type","Buy","Cur.","Sell","Cur.","Fee","Cur.","Exchange","Group","Comment","Date"
+"Trade","0.01","BTC","300","EUR","1.5","EUR","Kraken","","","2021-01-05 10:00:00"
+
+"Type","Buy Amount","Buy Currency","Sell Amount","Sell Currency","Fee","Fee Currency","Exchange","Trade-Group","Comment","Date","Tx-ID","Buy Value in Account Currency","Sell Value in Account Currency"
+"Staking","5","DOT","","","","","Kraken","","","2021-02-01 00:00:00","","100","",
+*/
+
+var requiredColumns = []string{
+	"Type",
+	"Buy Amount",
+	"Buy Currency",
+	"Sell Amount",
+	"Sell Currency",
+	"Date",
+}
+
+var timeFormats = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+}
+
+const (
+	buyValuePrefix  = "Buy Value in "
+	sellValuePrefix = "Sell Value in "
+	// accountCurrency is the value currency of the import format, the
+	// account currency must be EUR
+	accountCurrency = "Account Currency"
+)
+
+const tradeType = "trade"
+
+// incomingTypes maps the types of received amounts to the transaction type.
+var incomingTypes = map[string]transaction.Type{
+	"deposit":             transaction.Deposit,
+	"income":              transaction.Income,
+	"mining":              transaction.Income,
+	"mining (commercial)": transaction.Income,
+	"reward / bonus":      transaction.Income,
+	"staking":             transaction.Income,
+	"airdrop":             transaction.Income,
+	"lending income":      transaction.Income,
+	"interest income":     transaction.Income,
+	"masternode":          transaction.Income,
+	"gift / tip":          transaction.GiftReceived,
+}
+
+// nonTaxableTypes are the types of received amounts that are no taxable
+// income, they are acquired with a cost basis of 0€.
+var nonTaxableTypes = map[string]bool{
+	"income (non taxable)":  true,
+	"airdrop (non taxable)": true,
+}
+
+// outgoingTypes maps the types of sent amounts to the transaction type.
+// Spends and costs are payments with the currency.
+var outgoingTypes = map[string]transaction.Type{
+	"withdrawal": transaction.Withdrawal,
+	"gift":       transaction.GiftSent,
+	"donation":   transaction.GiftSent,
+	"lost":       transaction.Lost,
+	"stolen":     transaction.Lost,
+	"spend":      transaction.Sell,
+	"other fee":  transaction.Sell,
+	"margin fee": transaction.Sell,
+}
+
+// columns are the columns of an export, "-" is an empty value.
+type columns struct {
+	csvfile.Columns
+}
+
+func (c columns) get(rec []string, name string) string {
+	v := c.Get(rec, name)
+	if v == "-" {
+		return ""
+	}
+
+	return v
+}
+
+// format are the columns of an export and the currencies of the value
+// columns.
+type format struct {
+	cols              columns
+	buyValueCurrency  string
+	sellValueCurrency string
+}
+
+// parseHeader returns the columns of the header rec. The columns of both
+// formats are named like in the import format.
+func parseHeader(rec []string) (*format, error) {
+	f := format{cols: columns{csvfile.Columns{}}}
+
+	var prev string
+	for i, name := range rec {
+		name = csvfile.ColumnName(name)
+
+		switch {
+		case name == "Buy" || name == "Sell":
+			name += " Amount"
+		case name == "Cur.":
+			name = strings.TrimSuffix(prev, " Amount") + " Currency"
+		case strings.HasPrefix(name, buyValuePrefix):
+			f.buyValueCurrency = strings.TrimPrefix(name, buyValuePrefix)
+			name = "Buy Value"
+		case strings.HasPrefix(name, sellValuePrefix):
+			f.sellValueCurrency = strings.TrimPrefix(name, sellValuePrefix)
+			name = "Sell Value"
+		}
+
+		f.cols.Columns[name] = i
+		prev = name
+	}
+
+	if err := f.cols.Require(requiredColumns...); err != nil {
+		return nil, err
+	}
+
+	if f.buyValueCurrency == accountCurrency || f.sellValueCurrency == accountCurrency {
+		log.Warnf("import-cointracking: the values are in the account currency of CoinTracking, they are booked as EUR, the account currency must be EUR")
+	}
+
+	return &f, nil
+}
+
+// parseAmount parses an amount and its currency, an empty amount is 0, an
+// empty currency CurrencyUndef.
+func parseAmount(amount, currency string) (math.Decimal, transaction.Currency, error) {
+	var d math.Decimal
+	var err error
+
+	if len(amount) != 0 {
+		d, err = math.ParseDecimal(amount)
+		if err != nil {
+			return math.Decimal{}, 0, err
+		}
+	}
+
+	if len(currency) == 0 {
+		return d.Abs(), transaction.CurrencyUndef, nil
+	}
+
+	c, err := transaction.RegisterCurrency(currency)
+	if err != nil {
+		return math.Decimal{}, 0, fmt.Errorf("parsing %q failed: %s", currency, err)
+	}
+
+	return d.Abs(), c, nil
+}
+
+// valueCurrency returns the currency of a value column.
+func valueCurrency(v string) (transaction.Currency, error) {
+	if len(v) == 0 || v == accountCurrency {
+		return transaction.EUR, nil
+	}
+
+	c, err := transaction.RegisterCurrency(v)
+	if err != nil {
+		return 0, fmt.Errorf("parsing value currency %q failed: %s", v, err)
+	}
+
+	return c, nil
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+	var ids rowid.Generator
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	// the rows of the import format can end with a delimiter
+	csvReader.FieldsPerRecord = -1
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := parseHeader(rec)
+	if err != nil {
+		return nil, fmt.Errorf("import-cointracking: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := format.parseRow(rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-cointracking: %s: %v", err, rec)
+		}
+
+		if tx == nil {
+			continue
+		}
+
+		tx.ID = ids.ID(rec)
+		tx.Exchange = ExchangeName
+		tx.Wallet = format.cols.get(rec, "Exchange")
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+// parseRow returns the transaction of a row, nil is returned for fiat
+// deposits and withdrawals.
+func (f *format) parseRow(rec []string) (*transaction.Tx, error) {
+	var row universal.Row
+	var err error
+
+	cols := f.cols
+
+	row.Timestamp, err = csvfile.ParseTime(cols.get(rec, "Date"), time.UTC, timeFormats...)
+	if err != nil {
+		return nil, err
+	}
+
+	row.Received, row.ReceivedCurrency, err = parseAmount(cols.get(rec, "Buy Amount"), cols.get(rec, "Buy Currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	row.Sent, row.SentCurrency, err = parseAmount(cols.get(rec, "Sell Amount"), cols.get(rec, "Sell Currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	row.Fee, row.FeeCurrency, err = parseAmount(cols.get(rec, "Fee"), cols.get(rec, "Fee Currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	txType := strings.ToLower(cols.get(rec, "Type"))
+
+	switch {
+	case txType == tradeType:
+		row.Value, row.ValueCurrency, err = f.value(rec, "Sell Value", f.sellValueCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		if row.Value.IsZero() {
+			row.Value, row.ValueCurrency, err = f.value(rec, "Buy Value", f.buyValueCurrency)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return row.Trade()
+
+	case row.IsFiatTransfer():
+		log.Debugf("import-cointracking: skipping fiat transfer: %v", rec)
+		return nil, nil
+
+	case row.ReceivedCurrency != transaction.CurrencyUndef:
+		if nonTaxableTypes[txType] {
+			return row.NonTaxable()
+		}
+
+		t, exist := incomingTypes[txType]
+		if !exist {
+			return nil, fmt.Errorf("unsupported type %q of received amount", txType)
+		}
+
+		row.Value, row.ValueCurrency, err = f.value(rec, "Buy Value", f.buyValueCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		return row.Incoming(t)
+
+	case row.SentCurrency != transaction.CurrencyUndef:
+		t, exist := outgoingTypes[txType]
+		if !exist {
+			return nil, fmt.Errorf("unsupported type %q of sent amount", txType)
+		}
+
+		row.Value, row.ValueCurrency, err = f.value(rec, "Sell Value", f.sellValueCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		return row.Outgoing(t)
+	}
+
+	return nil, errors.New("buy and sell currency are missing")
+}
+
+// value returns the value of the value column name.
+func (f *format) value(rec []string, name, currency string) (math.Decimal, transaction.Currency, error) {
+	v := f.cols.get(rec, name)
+	if len(v) == 0 {
+		return math.Decimal{}, transaction.CurrencyUndef, nil
+	}
+
+	d, err := math.ParseDecimal(v)
+	if err != nil {
+		return math.Decimal{}, 0, err
+	}
+
+	c, err := valueCurrency(currency)
+	if err != nil {
+		return math.Decimal{}, 0, err
+	}
+
+	return d, c, nil
+}
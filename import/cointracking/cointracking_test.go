@@ -0,0 +1,67 @@
+package cointracking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/trade-list.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fiat deposit is skipped
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.01",
+			PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "1.5", FeeCurrency: transaction.EUR,
+		},
+		{Type: transaction.GiftSent, Currency: transaction.BTC, Quantity: "0.001"},
+		{Type: transaction.Lost, Currency: transaction.BTC, Quantity: "0.001"},
+	})
+
+	for _, tx := range res {
+		if tx.Account() != ExchangeName+"/Kraken" || len(tx.ID) == 0 {
+			t.Errorf("transaction %s has account %q and ID %q", tx, tx.Account(), tx.ID)
+		}
+	}
+}
+
+func TestFromCSVValues(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/trade-list-values.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importtest.Check(t, res, []importtest.Tx{
+		{Type: transaction.Income, Currency: importtest.Currency(t, "DOT"), Quantity: "5", PayCurrency: transaction.EUR, SpotPrice: "20"},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.001", PayCurrency: transaction.EUR, SpotPrice: "40000"},
+		// the value of a trade between cryptocurrencies is the sell value
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.02", PayCurrency: transaction.ETH, SpotPrice: "25", Value: "820"},
+		// non-taxable airdrops have a cost basis of 0€
+		{Type: transaction.Income, Currency: importtest.Currency(t, "UNI"), Quantity: "100", PayCurrency: transaction.EUR},
+		{Type: transaction.GiftReceived, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "40000"},
+		{Type: transaction.Withdrawal, Currency: transaction.ETH, Quantity: "0.1", Fees: "0.001", FeeCurrency: transaction.ETH},
+	})
+}
+
+func TestFromCSVUnsupportedType(t *testing.T) {
+	f, err := parseHeader([]string{"Type", "Buy", "Cur.", "Sell", "Cur.", "Fee", "Cur.", "Exchange", "Group", "Comment", "Date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.parseRow([]string{"Margin Profit", "1", "BTC", "", "", "", "", "Kraken", "", "", "2021-01-05 10:00:00"})
+	if err == nil {
+		t.Error("parsing a row with an unsupported type succeeded")
+	}
+}
@@ -0,0 +1,100 @@
+// Package csvfile contains helpers for the headers, columns and timestamps
+// of CSV exports.
+package csvfile
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ColumnName returns the name of a header column without surrounding
+// whitespace. The first column can contain an UTF-8 byte order mark, it is
+// removed.
+func ColumnName(v string) string {
+	return strings.TrimSpace(strings.TrimPrefix(v, "\ufeff"))
+}
+
+// CheckHeader returns an error if the names of the header columns rec do
+// not match names, the comparison is case insensitive.
+func CheckHeader(rec, names []string) error {
+	for i, name := range names {
+		if i >= len(rec) {
+			return fmt.Errorf("column %q is missing", name)
+		}
+
+		if !strings.EqualFold(ColumnName(rec[i]), name) {
+			return fmt.Errorf("unexpected column %q, expected %q", rec[i], name)
+		}
+	}
+
+	return nil
+}
+
+// Columns maps the names of the columns to their index.
+type Columns map[string]int
+
+// ParseHeader returns the columns of the header rec.
+func ParseHeader(rec []string) Columns {
+	cols := Columns{}
+	for i, name := range rec {
+		cols[ColumnName(name)] = i
+	}
+
+	return cols
+}
+
+// Index returns the index of the first of the columns names that exists.
+func (c Columns) Index(names ...string) (int, bool) {
+	for _, name := range names {
+		if idx, exist := c[name]; exist {
+			return idx, true
+		}
+	}
+
+	return 0, false
+}
+
+// Get returns the value of the first of the columns names that exists in
+// rec without surrounding whitespace. An empty string is returned if none
+// exists.
+func (c Columns) Get(rec []string, names ...string) string {
+	for _, name := range names {
+		idx, exist := c[name]
+		if exist && idx < len(rec) {
+			return strings.TrimSpace(rec[idx])
+		}
+	}
+
+	return ""
+}
+
+// Require returns an error for the first of the columns names that does
+// not exist.
+func (c Columns) Require(names ...string) error {
+	for _, name := range names {
+		if _, exist := c[name]; !exist {
+			return fmt.Errorf("column %q is missing", name)
+		}
+	}
+
+	return nil
+}
+
+// ParseTime parses v in the first of the layouts that matches. Timestamps
+// without time zone are in loc. The error of the last layout is returned
+// if none matches.
+func ParseTime(v string, loc *time.Location, layouts ...string) (time.Time, error) {
+	var err error
+
+	for _, layout := range layouts {
+		var ts time.Time
+
+		ts, err = time.ParseInLocation(layout, v, loc)
+		if err == nil {
+			return ts, nil
+		}
+	}
+
+	return time.Time{}, err
+}
@@ -0,0 +1,81 @@
+package csvfile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestColumns(t *testing.T) {
+	cols := ParseHeader([]string{"\ufeffDate", " Amount ", "Fee Currency"})
+	rec := []string{"2021-01-05", " 1.5 "}
+
+	if got := cols.Get(rec, "Date"); got != "2021-01-05" {
+		t.Errorf("Get(Date) = %q, want the value of the column with byte order mark", got)
+	}
+
+	if got := cols.Get(rec, "Quantity", "Amount"); got != "1.5" {
+		t.Errorf("Get(Quantity, Amount) = %q, want 1.5", got)
+	}
+
+	if got := cols.Get(rec, "Fee Currency"); got != "" {
+		t.Errorf("Get of a column after the end of the row = %q, want an empty value", got)
+	}
+
+	if idx, exist := cols.Index("Fee", "Fee Currency"); !exist || idx != 2 {
+		t.Errorf("Index(Fee, Fee Currency) = %d, %v, want 2, true", idx, exist)
+	}
+
+	if err := cols.Require("Date", "Amount"); err != nil {
+		t.Errorf("Require of existing columns returned error: %s", err)
+	}
+
+	if err := cols.Require("Date", "Type"); err == nil {
+		t.Error("Require of a missing column succeeded")
+	}
+}
+
+func TestCheckHeader(t *testing.T) {
+	header := []string{"Type", "Datetime", "Amount"}
+
+	if err := CheckHeader([]string{"\ufefftype", "Datetime ", "AMOUNT"}, header); err != nil {
+		t.Errorf("CheckHeader returned error: %s", err)
+	}
+
+	if err := CheckHeader([]string{"Type", "Date", "Amount"}, header); err == nil {
+		t.Error("CheckHeader of a different column succeeded")
+	}
+
+	if err := CheckHeader([]string{"Type", "Datetime"}, header); err == nil {
+		t.Error("CheckHeader of a missing column succeeded")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	layouts := []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"}
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{in: "2021-01-05T10:00:00Z", want: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC)},
+		{in: "2021-01-05 10:00", want: time.Date(2021, 1, 5, 8, 0, 0, 0, time.UTC)},
+		{in: "2021-01-05", want: time.Date(2021, 1, 4, 22, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		ts, err := ParseTime(tt.in, loc, layouts...)
+		if err != nil {
+			t.Errorf("ParseTime(%q) returned error: %s", tt.in, err)
+			continue
+		}
+
+		if !ts.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %s, want %s", tt.in, ts, tt.want)
+		}
+	}
+
+	if _, err := ParseTime("05.01.2021", loc, layouts...); err == nil {
+		t.Error("ParseTime of an unsupported format succeeded")
+	}
+}
@@ -0,0 +1,125 @@
+// Package electrum imports the history CSV exports of the Electrum bitcoin
+// wallet. The amounts must be exported in BTC.
+package electrum
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Electrum"
+
+type Import struct{}
+
+/* csv format, value is the change of the wallet balance including the fee,
+older versions do not export the fee:
+transaction_hash,label,confirmations,value,fiat_value,fee,fiat_fee,timestamp
+4a5e1e4b...,from exchange,1200,0.01,,,,2021-01-05 10:00:00
+9b0fc921...,to cold storage,900,-0.0051,,0.0001,,2021-02-01 18:30:00
+*/
+
+var requiredColumns = []string{
+	"transaction_hash",
+	"value",
+	"timestamp",
+}
+
+// timeFormats of the timestamp column, it is in the local time zone
+var timeFormats = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	cols := csvfile.ParseHeader(rec)
+	if err := cols.Require(requiredColumns...); err != nil {
+		return nil, fmt.Errorf("import-electrum: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := parseRow(cols, rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-electrum: %s: %v", err, rec)
+		}
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+func parseRow(cols csvfile.Columns, rec []string) (*transaction.Tx, error) {
+	hash := cols.Get(rec, "transaction_hash")
+	if len(hash) == 0 {
+		return nil, errors.New("transaction hash is empty")
+	}
+
+	ts, err := csvfile.ParseTime(cols.Get(rec, "timestamp"), time.Local, timeFormats...)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := math.ParseDecimal(cols.Get(rec, "value"))
+	if err != nil {
+		return nil, err
+	}
+
+	var fee math.Decimal
+	if v := cols.Get(rec, "fee"); len(v) != 0 {
+		fee, err = math.ParseDecimal(v)
+		if err != nil {
+			return nil, err
+		}
+		fee = fee.Abs()
+	}
+
+	tx := transaction.Tx{
+		ID:        hash,
+		Exchange:  ExchangeName,
+		Timestamp: ts.UTC(),
+		Type:      transaction.Deposit,
+		Currency:  transaction.BTC,
+		Quantity:  value,
+	}
+
+	if value.Sign() < 0 {
+		// the fee is paid by the sender, it is contained in the value
+		tx.Type = transaction.Withdrawal
+		tx.Quantity = value.Abs().Sub(fee)
+		tx.Fees = fee
+		tx.FeeCurrency = transaction.BTC
+	}
+
+	return &tx, nil
+}
@@ -0,0 +1,35 @@
+package electrum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/history.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// timestamps are in the local time zone
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.Local),
+			Type:      transaction.Deposit, Currency: transaction.BTC, Quantity: "0.01",
+		},
+		// the value contains the fee
+		{
+			Timestamp: time.Date(2021, 2, 1, 18, 30, 0, 0, time.Local),
+			Type:      transaction.Withdrawal, Currency: transaction.BTC, Quantity: "0.005", Fees: "0.0001", FeeCurrency: transaction.BTC,
+		},
+	})
+
+	if res[0].ID != "aaa1" {
+		t.Errorf("got ID %q, want the transaction hash aaa1", res[0].ID)
+	}
+}
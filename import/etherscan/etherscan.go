@@ -0,0 +1,528 @@
+// Package etherscan imports the CSV exports of the transactions of an
+// address from Etherscan and block explorers with the same format.
+//
+// Normal transactions, internal transactions and ERC-20 token transfers are
+// exported into separate files, the kind of a file is detected by its
+// columns. Received amounts are deposits, sent amounts withdrawals. The gas
+// fees of transactions that were sent from the address are removed from the
+// holdings. Tokens are booked as currencies that are keyed by their contract
+// address. Swaps consist of a withdrawal and a deposit, they have to be
+// changed to trades with the manual transactions file.
+package etherscan
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Etherscan"
+
+type Import struct{}
+
+/* csv formats, the currency of the value and fee columns is the native
+currency of the chain:
+
+normal transactions:
+"Txhash","Blockno","UnixTimestamp","DateTime (UTC)","From","To","ContractAddress","Value_IN(ETH)","Value_OUT(ETH)","CurrentValue @ $2000/Eth","TxnFee(ETH)","TxnFee(USD)","Historical $Price/Eth","Status","ErrCode","Method"
+
+internal transactions:
+"Txhash","Blockno","UnixTimestamp","DateTime (UTC)","ParentTxFrom","ParentTxTo","ParentTxETH_Value","From","TxTo","ContractAddress","Value_IN(ETH)","Value_OUT(ETH)","CurrentValue @ $2000/Eth","Historical $Price/Eth","Status","ErrCode","Type"
+
+ERC-20 token transfers:
+"Txhash","Blockno","UnixTimestamp","DateTime (UTC)","From","To","TokenValue","USDValueDayOfTx","ContractAddress","TokenName","TokenSymbol"
+*/
+
+type kind int
+
+const (
+	normalTxs kind = iota
+	internalTxs
+	tokenTransfers
+)
+
+var requiredColumns = map[kind][]string{
+	normalTxs:      {"Txhash", "UnixTimestamp", "From", "To", "Value_IN", "Value_OUT", "TxnFee"},
+	internalTxs:    {"Txhash", "UnixTimestamp", "From", "To", "Value_IN", "Value_OUT"},
+	tokenTransfers: {"Txhash", "UnixTimestamp", "From", "To", "TokenValue", "ContractAddress", "TokenSymbol"},
+}
+
+var (
+	// currencyColumnRe matches the names of columns that contain the
+	// currency of their values, e.g. Value_IN(ETH)
+	currencyColumnRe = regexp.MustCompile(`^(Value_IN|Value_OUT|TxnFee)\((.+)\)$`)
+	addressRe        = regexp.MustCompile(`0x[0-9a-fA-F]{40}`)
+)
+
+// format is the kind of an export, its columns and the currency of its
+// value and fee columns.
+type format struct {
+	kind   kind
+	cols   csvfile.Columns
+	native transaction.Currency
+}
+
+func parseHeader(rec []string) (*format, error) {
+	f := format{cols: csvfile.Columns{}}
+
+	for i, name := range rec {
+		name = csvfile.ColumnName(name)
+
+		switch name {
+		case "Transaction Hash":
+			name = "Txhash"
+		case "TxTo":
+			name = "To"
+		}
+
+		if m := currencyColumnRe.FindStringSubmatch(name); m != nil {
+			c, err := transaction.RegisterCurrency(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("parsing currency of column %q failed: %s", name, err)
+			}
+
+			if c == transaction.USD {
+				// TxnFee(USD) is the fee in USD
+				continue
+			}
+
+			if f.native != transaction.CurrencyUndef && f.native != c {
+				return nil, fmt.Errorf("columns have different currencies: %s, %s", f.native, c)
+			}
+
+			f.native = c
+			name = m[1]
+		}
+
+		f.cols[name] = i
+	}
+
+	_, hasTokenID := f.cols["TokenId"]
+	_, hasTokenSymbol := f.cols["TokenSymbol"]
+	_, hasParentTx := f.cols["ParentTxFrom"]
+
+	switch {
+	case hasTokenID:
+		return nil, errors.New("NFT transfers are not supported")
+	case hasTokenSymbol:
+		f.kind = tokenTransfers
+	case hasParentTx:
+		f.kind = internalTxs
+	default:
+		f.kind = normalTxs
+	}
+
+	if err := f.cols.Require(requiredColumns[f.kind]...); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// parseAmount parses a value of the export, values can contain thousands
+// separators.
+func parseAmount(v string) (math.Decimal, error) {
+	v = strings.ReplaceAll(v, ",", "")
+	if len(v) == 0 {
+		return math.Decimal{}, nil
+	}
+
+	return math.ParseDecimal(v)
+}
+
+// ownAddress returns the address that the file was exported for. It is
+// taken from the file name, Etherscan contains it in the names of exports.
+// Otherwise it is the only address that is the sender or receiver of all
+// rows.
+func ownAddress(path string, cols csvfile.Columns, rows [][]string) (string, error) {
+	if addr := addressRe.FindString(filepath.Base(path)); len(addr) != 0 {
+		return strings.ToLower(addr), nil
+	}
+
+	var candidates map[string]bool
+	for _, rec := range rows {
+		addrs := map[string]bool{
+			strings.ToLower(cols.Get(rec, "From")): true,
+			strings.ToLower(cols.Get(rec, "To")):   true,
+		}
+
+		if candidates == nil {
+			candidates = addrs
+			continue
+		}
+
+		for addr := range candidates {
+			if !addrs[addr] {
+				delete(candidates, addr)
+			}
+		}
+	}
+
+	delete(candidates, "")
+	if len(candidates) != 1 {
+		return "", errors.New("the address of the export can not be determined, the file name must contain it")
+	}
+
+	for addr := range candidates {
+		return addr, nil
+	}
+
+	return "", nil
+}
+
+// transfer is the sum of the rows of a transaction that have the same
+// currency.
+type transfer struct {
+	id       string
+	ts       time.Time
+	currency transaction.Currency
+	amount   math.Decimal // change of the balance without the fee
+	fee      math.Decimal
+}
+
+// importer converts the rows of an export to transfers.
+type importer struct {
+	*format
+	address   string
+	order     []*transfer
+	transfers map[string]*transfer
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	// rows of some exports end with a delimiter
+	csvReader.FieldsPerRecord = -1
+
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("import-etherscan: file is empty")
+	}
+
+	format, err := parseHeader(rows[0])
+	if err != nil {
+		return nil, fmt.Errorf("import-etherscan: %s", err)
+	}
+	rows = rows[1:]
+
+	address, err := ownAddress(path, format.cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("import-etherscan: %s", err)
+	}
+
+	imp := importer{
+		format:    format,
+		address:   address,
+		transfers: map[string]*transfer{},
+	}
+
+	if format.kind == tokenTransfers {
+		if err := imp.registerTokens(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rec := range rows {
+		if err := imp.add(rec); err != nil {
+			return nil, fmt.Errorf("import-etherscan: %s: %v", err, rec)
+		}
+	}
+
+	var results []*transaction.Tx
+	for _, t := range imp.order {
+		if tx := t.tx(); tx != nil {
+			results = append(results, tx)
+		}
+	}
+
+	return results, nil
+}
+
+// add adds a row to the transfer of its transaction and currency.
+func (imp *importer) add(rec []string) error {
+	var id string
+	var currency transaction.Currency
+	var amount, fee math.Decimal
+	var err error
+
+	hash := strings.ToLower(imp.cols.Get(rec, "Txhash"))
+	if len(hash) == 0 {
+		return errors.New("transaction hash is empty")
+	}
+
+	sec, err := strconv.ParseInt(imp.cols.Get(rec, "UnixTimestamp"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("parsing timestamp failed: %s", err)
+	}
+
+	sent := strings.EqualFold(imp.cols.Get(rec, "From"), imp.address)
+	received := strings.EqualFold(imp.cols.Get(rec, "To"), imp.address)
+
+	switch imp.kind {
+	case normalTxs, internalTxs:
+		id = hash
+		if imp.kind == internalTxs {
+			id += ":internal"
+		}
+		currency = imp.native
+
+		if imp.kind == normalTxs && sent {
+			fee, err = parseAmount(imp.cols.Get(rec, "TxnFee"))
+			if err != nil {
+				return err
+			}
+		}
+
+		// the values of failed transactions are not transferred,
+		// the fee is paid nevertheless
+		if strings.HasPrefix(strings.ToLower(imp.cols.Get(rec, "Status")), "error") {
+			break
+		}
+
+		in, err := parseAmount(imp.cols.Get(rec, "Value_IN"))
+		if err != nil {
+			return err
+		}
+
+		out, err := parseAmount(imp.cols.Get(rec, "Value_OUT"))
+		if err != nil {
+			return err
+		}
+
+		amount = in.Sub(out)
+
+	case tokenTransfers:
+		contract := strings.ToLower(imp.cols.Get(rec, "ContractAddress"))
+		id = hash + ":" + contract
+
+		currency = token(contract)
+		if currency == transaction.CurrencyUndef {
+			return nil
+		}
+
+		value, err := parseAmount(imp.cols.Get(rec, "TokenValue"))
+		if err != nil {
+			return err
+		}
+
+		if sent {
+			amount = amount.Sub(value)
+		}
+		if received {
+			amount = amount.Add(value)
+		}
+	}
+
+	t, exist := imp.transfers[id]
+	if !exist {
+		t = &transfer{id: id, ts: time.Unix(sec, 0).UTC(), currency: currency}
+		imp.transfers[id] = t
+		imp.order = append(imp.order, t)
+	}
+
+	t.amount = t.amount.Add(amount)
+	t.fee = t.fee.Add(fee)
+
+	return nil
+}
+
+// tokenRegistry maps the contract addresses of tokens to their currencies.
+type tokenRegistry struct {
+	mu         sync.Mutex
+	currencies map[string]transaction.Currency
+	contracts  map[transaction.Currency]string
+}
+
+// tokens is shared by all imports, the tokens of a contract are always
+// booked as the same currency and tokens of different contracts never
+// share one.
+var tokens = tokenRegistry{
+	currencies: map[string]transaction.Currency{},
+	contracts:  map[transaction.Currency]string{},
+}
+
+func (r *tokenRegistry) add(contract string, c transaction.Currency) {
+	r.currencies[contract] = c
+	r.contracts[c] = contract
+}
+
+// RegisterTokens registers the token currencies of previously imported
+// transactions, e.g. of the ledger. The tokens of their contracts are booked
+// as the same currencies in later imports.
+func RegisterTokens(txs []*transaction.Tx) {
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+
+	for _, tx := range txs {
+		if tx.Exchange != ExchangeName {
+			continue
+		}
+
+		// the IDs of token transfers end with the contract address
+		idx := strings.LastIndex(tx.ID, ":")
+		if idx == -1 {
+			continue
+		}
+
+		contract := tx.ID[idx+1:]
+		if !validContract(contract) {
+			continue
+		}
+
+		if _, exist := tokens.currencies[contract]; !exist {
+			tokens.add(contract, tx.Currency)
+		}
+	}
+}
+
+// validContract returns true if contract is a lower case address.
+func validContract(contract string) bool {
+	return len(contract) == 42 && addressRe.MatchString(contract) && contract == strings.ToLower(contract)
+}
+
+// registerTokens assigns currencies to the contracts of the token transfers
+// in rows that are not registered yet.
+// A token gets its symbol as currency if it is the only new contract with
+// the symbol and the symbol is not a predefined or fiat currency, the native
+// currency of the chain or the currency of a token of another contract.
+// Otherwise its currency is the symbol suffixed with the beginning of its
+// contract address. The result does not depend on the order of the rows.
+func (imp *importer) registerTokens(rows [][]string) error {
+	symbols := map[string]map[string]bool{}
+
+	for _, rec := range rows {
+		contract := strings.ToLower(imp.cols.Get(rec, "ContractAddress"))
+		if !validContract(contract) {
+			return fmt.Errorf("import-etherscan: invalid contract address %q: %v", contract, rec)
+		}
+
+		symbol := strings.ToUpper(imp.cols.Get(rec, "TokenSymbol"))
+		if symbols[symbol] == nil {
+			symbols[symbol] = map[string]bool{}
+		}
+		symbols[symbol][contract] = true
+	}
+
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+
+	var sorted []string
+	for symbol := range symbols {
+		sorted = append(sorted, symbol)
+	}
+	sort.Strings(sorted)
+
+	for _, symbol := range sorted {
+		var contracts []string
+		for contract := range symbols[symbol] {
+			if _, exist := tokens.currencies[contract]; !exist {
+				contracts = append(contracts, contract)
+			}
+		}
+		sort.Strings(contracts)
+
+		if len(contracts) == 0 {
+			continue
+		}
+
+		var reason string
+		if c, err := transaction.NewCurrency(symbol); err == nil {
+			if other, isToken := tokens.contracts[c]; isToken {
+				reason = "is the currency of token contract " + other
+			} else if c.Predefined() || c.IsFiat() || c == imp.native {
+				reason = "is the symbol of a native or fiat currency"
+			}
+		}
+		if len(reason) == 0 && len(contracts) > 1 {
+			reason = "is used by tokens of " + strconv.Itoa(len(contracts)) + " contracts"
+		}
+		taken := len(reason) != 0
+
+		for _, contract := range contracts {
+			name := symbol
+			if taken {
+				name = symbol + "-" + strings.ToUpper(contract[2:8])
+			}
+
+			c, err := transaction.RegisterCurrency(name)
+			if err != nil {
+				log.Warnf("import-etherscan: skipping transfers of token %q of contract %s: %s", symbol, contract, err)
+				continue
+			}
+
+			if other, exist := tokens.contracts[c]; exist {
+				return fmt.Errorf("import-etherscan: currency %s of token contract %s is already used by contract %s", c, contract, other)
+			}
+
+			if taken {
+				log.Warnf("import-etherscan: symbol %s of token contract %s %s, it is booked as %s",
+					symbol, contract, reason, c)
+			}
+
+			tokens.add(contract, c)
+		}
+	}
+
+	return nil
+}
+
+// token returns the registered currency of a token contract, CurrencyUndef is
+// returned for tokens with symbols that are not supported.
+func token(contract string) transaction.Currency {
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+
+	return tokens.currencies[contract]
+}
+
+// tx returns the deposit or withdrawal of the transfer, nil is returned if
+// nothing was transferred and no fee was paid.
+func (t *transfer) tx() *transaction.Tx {
+	if t.amount.IsZero() && t.fee.IsZero() {
+		return nil
+	}
+
+	tx := transaction.Tx{
+		ID:        t.id,
+		Exchange:  ExchangeName,
+		Timestamp: t.ts,
+		Type:      transaction.Deposit,
+		Currency:  t.currency,
+		Quantity:  t.amount,
+	}
+
+	// contract calls without a value only pay the gas fee
+	if t.amount.Sign() < 0 || !t.fee.IsZero() {
+		tx.Type = transaction.Withdrawal
+		tx.Quantity = t.amount.Abs()
+	}
+
+	if !t.fee.IsZero() {
+		tx.Fees = t.fee
+		tx.FeeCurrency = t.currency
+	}
+
+	return &tx
+}
@@ -0,0 +1,151 @@
+package etherscan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const address = "0x1111111111111111111111111111111111111111"
+
+func importFile(t *testing.T, path string) []*transaction.Tx {
+	t.Helper()
+
+	var p Import
+
+	res, err := p.FromCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestFromCSV(t *testing.T) {
+	tests := []struct {
+		path string
+		ids  []string
+		want []importtest.Tx
+	}{
+		{
+			path: "testdata/export-" + address + ".csv",
+			ids:  []string{"0xh1", "0xh2", "0xh3", "0xh4"},
+			want: []importtest.Tx{
+				// the fee is paid by the sender
+				{
+					Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+					Type:      transaction.Deposit, Currency: transaction.ETH, Quantity: "1.5",
+				},
+				{Type: transaction.Withdrawal, Currency: transaction.ETH, Quantity: "0.5", Fees: "0.002", FeeCurrency: transaction.ETH},
+				// a contract call without value
+				{Type: transaction.Withdrawal, Currency: transaction.ETH, Quantity: "0", Fees: "0.003", FeeCurrency: transaction.ETH},
+				// the value of a failed transaction is not transferred
+				{Type: transaction.Withdrawal, Currency: transaction.ETH, Quantity: "0", Fees: "0.001", FeeCurrency: transaction.ETH},
+			},
+		},
+		{
+			path: "testdata/export-internaltx-" + address + ".csv",
+			ids:  []string{"0xh3:internal"},
+			want: []importtest.Tx{
+				{Type: transaction.Deposit, Currency: transaction.ETH, Quantity: "0.1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res := importFile(t, tt.path)
+			importtest.Check(t, res, tt.want)
+
+			for i, tx := range res {
+				if tx.ID != tt.ids[i] {
+					t.Errorf("transaction %d: got ID %q, want %q", i, tx.ID, tt.ids[i])
+				}
+			}
+		})
+	}
+}
+
+// TestTokenTransfers imports all token transfers in one test, the
+// currencies of the token contracts are registered globally.
+func TestTokenTransfers(t *testing.T) {
+	res := importFile(t, "testdata/export-address-token-"+address+".csv")
+
+	// USDT is the symbol of two contracts, ETH the native currency, the
+	// spam symbol of a contract with a valid symbol is ignored
+	usdtA := importtest.Currency(t, "USDT-AAAAAA")
+	usdtB := importtest.Currency(t, "USDT-BBBBBB")
+	ethC := importtest.Currency(t, "ETH-CCCCCC")
+
+	importtest.Check(t, res, []importtest.Tx{
+		{Type: transaction.Deposit, Currency: usdtB, Quantity: "5"},
+		{Type: transaction.Deposit, Currency: ethC, Quantity: "5"},
+		{Type: transaction.Deposit, Currency: usdtA, Quantity: "1000.5"},
+		{Type: transaction.Withdrawal, Currency: usdtA, Quantity: "200"},
+		{Type: transaction.Deposit, Currency: usdtB, Quantity: "5"},
+		{Type: transaction.Deposit, Currency: usdtB, Quantity: "5"},
+	})
+
+	if want := "0xh5:0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"; res[2].ID != want {
+		t.Errorf("got ID %q, want %q", res[2].ID, want)
+	}
+
+	// a later import books the known contract as the same currency, the
+	// symbol of a fiat currency is suffixed
+	res = importFile(t, "testdata/later/export-address-token-"+address+".csv")
+	importtest.Check(t, res, []importtest.Tx{
+		{Type: transaction.Deposit, Currency: usdtA, Quantity: "3"},
+		{Type: transaction.Deposit, Currency: importtest.Currency(t, "EUR-DDDDDD"), Quantity: "3"},
+	})
+
+	// tokens of ledger transactions keep their currency
+	ledgerToken, err := transaction.RegisterCurrency("LEDGERTKN")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterTokens([]*transaction.Tx{{
+		ID:       "0xl1:0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee",
+		Exchange: ExchangeName,
+		Currency: ledgerToken,
+	}})
+
+	path := filepath.Join(t.TempDir(), "export-address-token-"+address+".csv")
+	err = os.WriteFile(path, []byte(`"Txhash","Blockno","UnixTimestamp","DateTime (UTC)","From","To","TokenValue","USDValueDayOfTx","ContractAddress","TokenName","TokenSymbol"
+"0xl2","10","1613004200","","0x2222222222222222222222222222222222222222","0x1111111111111111111111111111111111111111","7","","0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee","Renamed","NEWTKN"
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importtest.Check(t, importFile(t, path), []importtest.Tx{
+		{Type: transaction.Deposit, Currency: ledgerToken, Quantity: "7"},
+	})
+}
+
+func TestOwnAddress(t *testing.T) {
+	cols := csvfile.Columns{"From": 0, "To": 1}
+	rows := [][]string{
+		{"0x2222222222222222222222222222222222222222", address},
+		{address, "0x3333333333333333333333333333333333333333"},
+	}
+
+	addr, err := ownAddress("transactions.csv", cols, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != address {
+		t.Errorf("got address %s, want %s", addr, address)
+	}
+
+	_, err = ownAddress("transactions.csv", cols, rows[:1])
+	if err == nil {
+		t.Error("determining the address of a file with a single row succeeded")
+	}
+}
@@ -0,0 +1,260 @@
+// Package gemini imports the trades of the transaction history CSV exports
+// of Gemini.
+package gemini
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Gemini"
+
+type Import struct{}
+
+/* csv format, the file contains an amount, fee and balance column per
+currency, negative values are in parentheses:
+Date,Time (UTC),Type,Symbol,Specification,Liquidity Indicator,Trading Fee Rate (bps),USD Amount USD,Fee (USD) USD,USD Balance USD,BTC Amount BTC,Fee (BTC) BTC,BTC Balance BTC,Trade ID,Order ID
+2021-01-05,10:00:00.123,Buy,BTCUSD,Flex Buy,Taker,35,($300.00),($1.05),$698.95,0.01 BTC,,0.01 BTC,123456,987654
+*/
+
+const timeLayout = "2006-01-02 15:04:05.999"
+
+var (
+	amountColumn = regexp.MustCompile(`^(\S+) Amount \S+$`)
+	feeColumn    = regexp.MustCompile(`^Fee \((\S+)\) \S+$`)
+)
+
+// format are the column indices of an export.
+type format struct {
+	fields                              int
+	date, time, txType, symbol, tradeID int
+	// amounts and fees contain the column index per currency symbol
+	amounts map[string]int
+	fees    map[string]int
+}
+
+func parseHeader(rec []string) (*format, error) {
+	f := format{
+		fields:  len(rec),
+		amounts: map[string]int{},
+		fees:    map[string]int{},
+	}
+
+	cols := csvfile.ParseHeader(rec)
+	for name, i := range cols {
+		if m := amountColumn.FindStringSubmatch(name); m != nil {
+			f.amounts[m[1]] = i
+		}
+
+		if m := feeColumn.FindStringSubmatch(name); m != nil {
+			f.fees[m[1]] = i
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		idx  *int
+	}{
+		{"Date", &f.date},
+		{"Time (UTC)", &f.time},
+		{"Type", &f.txType},
+		{"Symbol", &f.symbol},
+		{"Trade ID", &f.tradeID},
+	} {
+		var exist bool
+
+		*c.idx, exist = cols[c.name]
+		if !exist {
+			return nil, fmt.Errorf("column %q is missing", c.name)
+		}
+	}
+
+	return &f, nil
+}
+
+// parseSymbol splits a trading pair, e.g. BTCUSD, into the currencies of
+// the amount columns.
+func (f *format) parseSymbol(v string) (base, quote string, err error) {
+	for i := 1; i < len(v); i++ {
+		_, baseExist := f.amounts[v[:i]]
+		_, quoteExist := f.amounts[v[i:]]
+
+		if baseExist && quoteExist {
+			return v[:i], v[i:], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("parsing symbol %q failed: no amount columns for its currencies", v)
+}
+
+// parseAmount parses the absolute value of an amount, e.g. "($1,234.50)"
+// or "0.01 BTC". An empty value is 0.
+func parseAmount(v string) (math.Decimal, error) {
+	str := strings.NewReplacer("(", "", ")", "", "$", "", "€", "", "£", "", ",", "").Replace(v)
+
+	fields := strings.Fields(str)
+	if len(fields) == 0 {
+		return math.Decimal{}, nil
+	}
+
+	d, err := math.ParseDecimal(fields[0])
+	if err != nil {
+		return math.Decimal{}, err
+	}
+
+	return d.Abs(), nil
+}
+
+// amount returns the value of the column of currency in cols, 0 is
+// returned if the column does not exist.
+func amount(rec []string, cols map[string]int, currency string) (math.Decimal, error) {
+	idx, exist := cols[currency]
+	if !exist || idx >= len(rec) {
+		return math.Decimal{}, nil
+	}
+
+	return parseAmount(rec[idx])
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	// the last row contains only the balances, it can have less fields
+	csvReader.FieldsPerRecord = -1
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := parseHeader(rec)
+	if err != nil {
+		return nil, fmt.Errorf("import-gemini: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rec) < format.fields || len(rec[format.date]) == 0 {
+			log.Debugf("import-gemini: skipping row without transaction: %v", rec)
+			continue
+		}
+
+		var txType transaction.Type
+		switch strings.ToLower(rec[format.txType]) {
+		case "buy":
+			txType = transaction.Buy
+		case "sell":
+			txType = transaction.Sell
+		default:
+			log.Infof("import-gemini: skipping unsupported transaction type %q: %v", rec[format.txType], rec)
+			continue
+		}
+
+		tx, err := format.parseTrade(rec, txType)
+		if err != nil {
+			return nil, fmt.Errorf("import-gemini: %s: %v", err, rec)
+		}
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+func (f *format) parseTrade(rec []string, txType transaction.Type) (*transaction.Tx, error) {
+	id := rec[f.tradeID]
+	if len(id) == 0 {
+		return nil, errors.New("trade id is empty")
+	}
+
+	ts, err := time.Parse(timeLayout, rec[f.date]+" "+rec[f.time])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[f.date]+" "+rec[f.time], err)
+	}
+
+	baseSym, quoteSym, err := f.parseSymbol(rec[f.symbol])
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := transaction.RegisterCurrency(baseSym)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", baseSym, err)
+	}
+
+	quote, err := transaction.RegisterCurrency(quoteSym)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", quoteSym, err)
+	}
+
+	quantity, err := amount(rec, f.amounts, baseSym)
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity.IsZero() {
+		return nil, fmt.Errorf("%s amount is 0", baseSym)
+	}
+
+	total, err := amount(rec, f.amounts, quoteSym)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := transaction.Tx{
+		ID:          id,
+		Exchange:    ExchangeName,
+		Timestamp:   ts,
+		Type:        txType,
+		PayCurrency: quote,
+		Currency:    base,
+		Quantity:    quantity,
+		SpotPrice:   total.Quo(quantity, math.DivScale, math.RoundHalfEven).Normalize(),
+	}
+
+	// the fee is paid in the quote currency, for some orders in the base
+	// currency
+	for _, sym := range []string{quoteSym, baseSym} {
+		fee, err := amount(rec, f.fees, sym)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fee.IsZero() {
+			tx.Fees = fee
+			tx.FeeCurrency, err = transaction.RegisterCurrency(sym)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %q failed: %s", sym, err)
+			}
+
+			break
+		}
+	}
+
+	return &tx, nil
+}
@@ -0,0 +1,56 @@
+package gemini
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/transaction-history.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deposits and the balance row are skipped
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 123*int(time.Millisecond), time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "1.05", FeeCurrency: transaction.EUR,
+		},
+		// the fee is paid in the base currency
+		{
+			Timestamp: time.Date(2021, 2, 1, 8, 30, 0, 500*int(time.Millisecond), time.UTC),
+			Type:      transaction.Sell, Currency: transaction.BTC, Quantity: "0.03", PayCurrency: transaction.EUR, SpotPrice: "40000", Fees: "0.00001", FeeCurrency: transaction.BTC,
+		},
+	})
+
+	if res[0].ID != "123456" {
+		t.Errorf("got ID %q, want the trade ID 123456", res[0].ID)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]string{
+		"($1,234.50)": "1234.5",
+		"€698.95":     "698.95",
+		"(0.01 BTC)":  "0.01",
+		"":            "0",
+	}
+
+	for v, want := range tests {
+		got, err := parseAmount(v)
+		if err != nil {
+			t.Errorf("parsing %q failed: %s", v, err)
+			continue
+		}
+
+		if got.Cmp(importtest.Decimal(t, want)) != 0 {
+			t.Errorf("parsing %q returned %s, want %s", v, got, want)
+		}
+	}
+}
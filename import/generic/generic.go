@@ -0,0 +1,449 @@
+// Package generic imports CSV files of exchanges without a dedicated
+// importer. The columns and values are described by a mapping in a JSON
+// file:
+//
+//	{
+//	  "exchange": "OTC Desk",
+//	  "delimiter": ";",
+//	  "decimal_separator": ",",
+//	  "timestamp_layout": "02.01.2006 15:04",
+//	  "columns": {
+//	    "timestamp": "Datum",
+//	    "type": "Art",
+//	    "base": "Coin",
+//	    "quote": "Währung",
+//	    "quantity": "Menge",
+//	    "price": "Kurs",
+//	    "fee": "Gebühr",
+//	    "fee_currency": 8,
+//	    "id": "Referenz"
+//	  },
+//	  "types": {"Kauf": "buy", "Verkauf": "sell"}
+//	}
+//
+// Columns are referenced by their name in the header row or by their index,
+// starting at 0. timestamp, type, base, quantity and price are required.
+// Without quote column the price is in €, without fee_currency column fees
+// are in the quote currency and without id column the ID is derived from
+// the content of the row.
+// timestamp_layout is a Go time layout, the default is RFC 3339. Timestamps
+// without time zone are in UTC. If decimal_separator is ",", dots are
+// treated as thousands separators, otherwise commas.
+// types maps the values of the type column to the transaction types buy,
+// sell, deposit, withdrawal, income, gift-received, gift-sent and lost.
+// Values that are not mapped are parsed as transaction type. Rows whose type
+// is mapped to "skip" are ignored.
+// If no_header is true, the file has no header row and all columns must be
+// referenced by their index.
+package generic
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/import/rowid"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// DefaultExchangeName is the exchange of the transactions if the mapping
+// does not define one.
+const DefaultExchangeName = "Generic"
+
+// SkipType is the type value that ignores rows.
+const SkipType = "skip"
+
+// Column references a column by its name or its index.
+type Column struct {
+	Name  string
+	Index int
+	set   bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it accepts strings as names
+// and numbers as indices.
+func (c *Column) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if str == "null" {
+		return nil
+	}
+
+	if name, err := strconv.Unquote(str); err == nil {
+		if len(name) == 0 {
+			return errors.New("column name is empty")
+		}
+
+		*c = Column{Name: name, set: true}
+		return nil
+	}
+
+	idx, err := strconv.Atoi(str)
+	if err != nil || idx < 0 {
+		return fmt.Errorf("invalid column %s, expected a name or an index", str)
+	}
+
+	*c = Column{Index: idx, set: true}
+
+	return nil
+}
+
+// Columns references the columns of the transaction fields.
+type Columns struct {
+	Timestamp   Column `json:"timestamp"`
+	Type        Column `json:"type"`
+	Base        Column `json:"base"`
+	Quote       Column `json:"quote"`
+	Quantity    Column `json:"quantity"`
+	Price       Column `json:"price"`
+	Fee         Column `json:"fee"`
+	FeeCurrency Column `json:"fee_currency"`
+	ID          Column `json:"id"`
+}
+
+// Mapping describes the format of a CSV file.
+type Mapping struct {
+	Exchange         string            `json:"exchange"`
+	Delimiter        string            `json:"delimiter"`
+	DecimalSeparator string            `json:"decimal_separator"`
+	TimestampLayout  string            `json:"timestamp_layout"`
+	NoHeader         bool              `json:"no_header"`
+	Columns          Columns           `json:"columns"`
+	Types            map[string]string `json:"types"`
+}
+
+// ReadMapping reads and validates a mapping file.
+func ReadMapping(path string) (*Mapping, error) {
+	var m Mapping
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %s", path, err)
+	}
+
+	err = m.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+func (m *Mapping) validate() error {
+	for _, c := range []struct {
+		name string
+		col  Column
+	}{
+		{"timestamp", m.Columns.Timestamp},
+		{"type", m.Columns.Type},
+		{"base", m.Columns.Base},
+		{"quantity", m.Columns.Quantity},
+		{"price", m.Columns.Price},
+	} {
+		if !c.col.set {
+			return fmt.Errorf("the %s column is not mapped", c.name)
+		}
+	}
+
+	if len([]rune(m.Delimiter)) > 1 {
+		return fmt.Errorf("delimiter %q is not a single character", m.Delimiter)
+	}
+
+	switch m.DecimalSeparator {
+	case "", ".", ",":
+	default:
+		return fmt.Errorf("unsupported decimal separator %q, supported are . and ,", m.DecimalSeparator)
+	}
+
+	for k, v := range m.Types {
+		if v == SkipType {
+			continue
+		}
+
+		if _, err := transaction.NewType(v); err != nil {
+			return fmt.Errorf("type %q: %w: %q", k, err, v)
+		}
+	}
+
+	return nil
+}
+
+// ExchangeName returns the exchange of the imported transactions.
+func (m *Mapping) ExchangeName() string {
+	if len(m.Exchange) == 0 {
+		return DefaultExchangeName
+	}
+
+	return m.Exchange
+}
+
+// Import imports CSV files according to a Mapping.
+type Import struct {
+	Mapping *Mapping
+}
+
+// indices are the resolved column indices of a file, -1 if a column is
+// not mapped.
+type indices struct {
+	timestamp, txType, base, quote, quantity, price, fee, feeCurrency, id int
+	// max is the highest index
+	max int
+}
+
+func (m *Mapping) indices(header []string) (*indices, error) {
+	names := csvfile.ParseHeader(header)
+
+	resolve := func(c Column) (int, error) {
+		if !c.set {
+			return -1, nil
+		}
+
+		if len(c.Name) == 0 {
+			return c.Index, nil
+		}
+
+		if m.NoHeader {
+			return 0, fmt.Errorf("column %q is referenced by name, the file has no header", c.Name)
+		}
+
+		idx, exist := names[c.Name]
+		if !exist {
+			return 0, fmt.Errorf("column %q does not exist", c.Name)
+		}
+
+		return idx, nil
+	}
+
+	var res indices
+	for _, c := range []struct {
+		col Column
+		idx *int
+	}{
+		{m.Columns.Timestamp, &res.timestamp},
+		{m.Columns.Type, &res.txType},
+		{m.Columns.Base, &res.base},
+		{m.Columns.Quote, &res.quote},
+		{m.Columns.Quantity, &res.quantity},
+		{m.Columns.Price, &res.price},
+		{m.Columns.Fee, &res.fee},
+		{m.Columns.FeeCurrency, &res.feeCurrency},
+		{m.Columns.ID, &res.id},
+	} {
+		idx, err := resolve(c.col)
+		if err != nil {
+			return nil, err
+		}
+
+		*c.idx = idx
+		if idx > res.max {
+			res.max = idx
+		}
+	}
+
+	return &res, nil
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+	var ids rowid.Generator
+
+	if p.Mapping == nil {
+		return nil, errors.New("import-generic: no column mapping was passed")
+	}
+	m := p.Mapping
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	if len(m.Delimiter) != 0 {
+		csvReader.Comma = []rune(m.Delimiter)[0]
+	}
+
+	var header []string
+	if !m.NoHeader {
+		header, err = csvReader.Read()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idx, err := m.indices(header)
+	if err != nil {
+		return nil, fmt.Errorf("import-generic: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := m.parseRow(idx, rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-generic: %s: %v", err, rec)
+		}
+
+		if tx == nil {
+			continue
+		}
+
+		if idx.id == -1 {
+			tx.ID = ids.ID(rec)
+		}
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+// get returns the value of the column idx, an empty string is returned
+// for unmapped columns.
+func get(rec []string, idx int) string {
+	if idx == -1 {
+		return ""
+	}
+
+	return strings.TrimSpace(rec[idx])
+}
+
+func (m *Mapping) parseDecimal(v string) (math.Decimal, error) {
+	var str string
+
+	if m.DecimalSeparator == "," {
+		str = strings.NewReplacer(".", "", ",", ".").Replace(v)
+	} else {
+		str = strings.ReplaceAll(v, ",", "")
+	}
+
+	if len(str) == 0 {
+		return math.Decimal{}, nil
+	}
+
+	return math.ParseDecimal(str)
+}
+
+func (m *Mapping) parseType(v string) (transaction.Type, bool, error) {
+	if mapped, exist := m.Types[v]; exist {
+		v = mapped
+	}
+
+	if v == SkipType {
+		return transaction.TypeUndef, true, nil
+	}
+
+	txType, err := transaction.NewType(v)
+	if err != nil {
+		return transaction.TypeUndef, false, fmt.Errorf("parsing %q failed: %s", v, err)
+	}
+
+	return txType, false, nil
+}
+
+func (m *Mapping) parseTime(v string) (time.Time, error) {
+	layout := m.TimestampLayout
+	if len(layout) == 0 {
+		layout = time.RFC3339
+	}
+
+	return time.Parse(layout, v)
+}
+
+// parseRow returns the transaction of a row, nil is returned for skipped
+// rows.
+func (m *Mapping) parseRow(idx *indices, rec []string) (*transaction.Tx, error) {
+	if len(rec) <= idx.max {
+		return nil, fmt.Errorf("row has %d columns, expected at least %d", len(rec), idx.max+1)
+	}
+
+	typeV := get(rec, idx.txType)
+	txType, skip, err := m.parseType(typeV)
+	if err != nil {
+		return nil, err
+	}
+
+	if skip {
+		log.Debugf("import-generic: skipping row of type %q: %v", typeV, rec)
+		return nil, nil
+	}
+
+	ts, err := m.parseTime(get(rec, idx.timestamp))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", get(rec, idx.timestamp), err)
+	}
+
+	baseV := get(rec, idx.base)
+	base, err := transaction.RegisterCurrency(baseV)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", baseV, err)
+	}
+
+	quote := transaction.EUR
+	if quoteV := get(rec, idx.quote); len(quoteV) != 0 {
+		quote, err = transaction.RegisterCurrency(quoteV)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %s", quoteV, err)
+		}
+	}
+
+	quantity, err := m.parseDecimal(get(rec, idx.quantity))
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := m.parseDecimal(get(rec, idx.price))
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := m.parseDecimal(get(rec, idx.fee))
+	if err != nil {
+		return nil, err
+	}
+
+	var feeCurrency transaction.Currency
+	if feeCurrencyV := get(rec, idx.feeCurrency); len(feeCurrencyV) != 0 {
+		feeCurrency, err = transaction.RegisterCurrency(feeCurrencyV)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %s", feeCurrencyV, err)
+		}
+	}
+
+	id := get(rec, idx.id)
+	if idx.id != -1 && len(id) == 0 {
+		return nil, errors.New("id is empty")
+	}
+
+	return &transaction.Tx{
+		ID:          id,
+		Exchange:    m.ExchangeName(),
+		Timestamp:   ts,
+		Type:        txType,
+		PayCurrency: quote,
+		Currency:    base,
+		Quantity:    quantity.Abs(),
+		SpotPrice:   price,
+		Fees:        fee.Abs(),
+		FeeCurrency: feeCurrency,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package generic
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  string
+		path     string
+		exchange string
+		want     []importtest.Tx
+	}{
+		{
+			name:     "decimal comma",
+			mapping:  "testdata/otc.json",
+			path:     "testdata/otc.csv",
+			exchange: "OTC Desk",
+			// the row of type Einzahlung is skipped, fees are in
+			// the quote currency
+			want: []importtest.Tx{
+				{
+					Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+					Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.5", PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "10",
+				},
+				{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.25", PayCurrency: transaction.EUR, SpotPrice: "40000", Fees: "5"},
+			},
+		},
+		{
+			name:     "column indices",
+			mapping:  "testdata/no-header.json",
+			path:     "testdata/no-header.csv",
+			exchange: DefaultExchangeName,
+			want: []importtest.Tx{
+				{
+					Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+					Type:      transaction.Buy, Currency: transaction.ETH, Quantity: "2", PayCurrency: transaction.EUR, SpotPrice: "1000",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ReadMapping(tt.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := Import{Mapping: m}
+			res, err := p.FromCSV(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			importtest.Check(t, res, tt.want)
+
+			for _, tx := range res {
+				if tx.Exchange != tt.exchange {
+					t.Errorf("got exchange %q, want %q", tx.Exchange, tt.exchange)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		separator string
+		in        string
+		want      string
+	}{
+		{separator: "", in: "1,234.5", want: "1234.5"},
+		{separator: ".", in: "0.25", want: "0.25"},
+		{separator: ",", in: "1.234,5", want: "1234.5"},
+		{separator: ",", in: "0,25", want: "0.25"},
+		{separator: ",", in: "", want: "0"},
+	}
+
+	for _, tt := range tests {
+		m := Mapping{DecimalSeparator: tt.separator}
+
+		got, err := m.parseDecimal(tt.in)
+		if err != nil {
+			t.Errorf("parsing %q with decimal separator %q failed: %s", tt.in, tt.separator, err)
+			continue
+		}
+
+		if got.Cmp(importtest.Decimal(t, tt.want)) != 0 {
+			t.Errorf("parsing %q with decimal separator %q returned %s, want %s", tt.in, tt.separator, got, tt.want)
+		}
+	}
+}
+
+func TestMappingValidate(t *testing.T) {
+	const columns = `"columns": {"timestamp": "Date", "type": 1, "base": "Coin", "quantity": "Amount", "price": "Price"}`
+
+	tests := []struct {
+		name    string
+		mapping string
+		valid   bool
+	}{
+		{name: "valid", mapping: `{` + columns + `, "types": {"Kauf": "buy", "Fiat": "skip"}}`, valid: true},
+		{name: "unmapped price", mapping: `{"columns": {"timestamp": "Date", "type": 1, "base": "Coin", "quantity": "Amount"}}`},
+		{name: "delimiter", mapping: `{"delimiter": ";;", ` + columns + `}`},
+		{name: "decimal separator", mapping: `{"decimal_separator": "'", ` + columns + `}`},
+		{name: "unknown type", mapping: `{` + columns + `, "types": {"Kauf": "purchase"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Mapping
+
+			err := json.Unmarshal([]byte(tt.mapping), &m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = m.validate()
+			if tt.valid && err != nil {
+				t.Errorf("validate returned error: %s", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Error("validate succeeded")
+			}
+		})
+	}
+}
+
+func TestColumnUnmarshalJSON(t *testing.T) {
+	for _, v := range []string{`""`, `-1`, `1.5`} {
+		var c Column
+
+		if err := json.Unmarshal([]byte(v), &c); err == nil {
+			t.Errorf("unmarshaling column %s succeeded", v)
+		}
+	}
+}
+
+func TestColumnByNameWithoutHeader(t *testing.T) {
+	m := Mapping{
+		NoHeader: true,
+		Columns: Columns{
+			Timestamp: Column{Index: 0, set: true},
+			Type:      Column{Name: "Type", set: true},
+		},
+	}
+
+	if _, err := m.indices(nil); err == nil {
+		t.Error("resolving a column name of a file without header succeeded")
+	}
+}
@@ -0,0 +1,95 @@
+// Package importtest contains helpers for the tests of the importers.
+package importtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// Tx is an expected transaction. Empty amounts are 0, a zero Timestamp is
+// not compared.
+type Tx struct {
+	Timestamp   time.Time
+	Type        transaction.Type
+	Currency    transaction.Currency
+	Quantity    string
+	PayCurrency transaction.Currency
+	SpotPrice   string
+	Fees        string
+	FeeCurrency transaction.Currency
+	Value       string
+}
+
+// Currency returns the registered currency symbol.
+func Currency(t *testing.T, symbol string) transaction.Currency {
+	t.Helper()
+
+	c, err := transaction.NewCurrency(symbol)
+	if err != nil {
+		t.Fatalf("currency %s: %s", symbol, err)
+	}
+
+	return c
+}
+
+// Decimal parses v, an empty value is 0.
+func Decimal(t *testing.T, v string) math.Decimal {
+	t.Helper()
+
+	if len(v) == 0 {
+		return math.Decimal{}
+	}
+
+	d, err := math.ParseDecimal(v)
+	if err != nil {
+		t.Fatalf("parsing expected amount %q failed: %s", v, err)
+	}
+
+	return d
+}
+
+// Check reports an error for every transaction of got that differs from
+// the one of want at the same position.
+func Check(t *testing.T, got []*transaction.Tx, want []Tx) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		for _, tx := range got {
+			t.Log(tx)
+		}
+		t.Fatalf("got %d transactions, want %d", len(got), len(want))
+	}
+
+	for i, w := range want {
+		tx := got[i]
+
+		if !w.Timestamp.IsZero() && !tx.Timestamp.Equal(w.Timestamp) {
+			t.Errorf("transaction %d: timestamp is %s, want %s", i, tx.Timestamp, w.Timestamp)
+		}
+
+		if tx.Type != w.Type || tx.Currency != w.Currency || tx.PayCurrency != w.PayCurrency || tx.FeeCurrency != w.FeeCurrency {
+			t.Errorf("transaction %d: got %s %s paid with %s, fees in %s, want %s %s paid with %s, fees in %s",
+				i, tx.Type, tx.Currency, tx.PayCurrency, tx.FeeCurrency, w.Type, w.Currency, w.PayCurrency, w.FeeCurrency)
+		}
+
+		amounts := []struct {
+			name string
+			got  math.Decimal
+			want string
+		}{
+			{"quantity", tx.Quantity, w.Quantity},
+			{"spot price", tx.SpotPrice, w.SpotPrice},
+			{"fees", tx.Fees, w.Fees},
+			{"value", tx.Value, w.Value},
+		}
+
+		for _, a := range amounts {
+			if a.got.Cmp(Decimal(t, a.want)) != 0 {
+				t.Errorf("transaction %d: %s is %s, want %s", i, a.name, a.got, Decimal(t, a.want))
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package koinly imports CSV files in the Koinly universal format.
+package koinly
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/import/rowid"
+	"github.com/fho/cryptotax/import/universal"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "Koinly"
+
+type Import struct{}
+
+/* csv format:
+Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,Net Worth Amount,Net Worth Currency,Label,Description,TxHash
+2021-01-05 10:00 UTC,300,EUR,0.01,BTC,1.5,EUR,300,EUR,,,
+2021-02-01 00:00 UTC,,,5,DOT,,,100,EUR,staking,,
+*/
+
+var requiredColumns = []string{
+	"Date",
+	"Sent Amount",
+	"Sent Currency",
+	"Received Amount",
+	"Received Currency",
+}
+
+var timeFormats = []string{
+	"2006-01-02 15:04 MST",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// incomingLabels maps the labels of received amounts to the transaction
+// type, unlabeled receipts are deposits.
+var incomingLabels = map[string]transaction.Type{
+	"":                 transaction.Deposit,
+	"airdrop":          transaction.Income,
+	"fork":             transaction.Income,
+	"mining":           transaction.Income,
+	"reward":           transaction.Income,
+	"staking":          transaction.Income,
+	"income":           transaction.Income,
+	"other income":     transaction.Income,
+	"lending interest": transaction.Income,
+	"loan interest":    transaction.Income,
+	"gift":             transaction.GiftReceived,
+}
+
+// outgoingLabels maps the labels of sent amounts to the transaction type,
+// unlabeled sends are withdrawals. Costs are payments with the currency.
+var outgoingLabels = map[string]transaction.Type{
+	"":           transaction.Withdrawal,
+	"gift":       transaction.GiftSent,
+	"donation":   transaction.GiftSent,
+	"lost":       transaction.Lost,
+	"stolen":     transaction.Lost,
+	"cost":       transaction.Sell,
+	"margin fee": transaction.Sell,
+}
+
+// parseAmount parses an amount and its currency, an empty amount is 0, an
+// empty currency CurrencyUndef.
+func parseAmount(amount, currency string) (math.Decimal, transaction.Currency, error) {
+	var d math.Decimal
+	var err error
+
+	if len(amount) != 0 {
+		d, err = math.ParseDecimal(amount)
+		if err != nil {
+			return math.Decimal{}, 0, err
+		}
+	}
+
+	if len(currency) == 0 {
+		return d.Abs(), transaction.CurrencyUndef, nil
+	}
+
+	c, err := transaction.RegisterCurrency(currency)
+	if err != nil {
+		return math.Decimal{}, 0, fmt.Errorf("parsing %q failed: %s", currency, err)
+	}
+
+	return d.Abs(), c, nil
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+	var ids rowid.Generator
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	cols := csvfile.ParseHeader(rec)
+	if err := cols.Require(requiredColumns...); err != nil {
+		return nil, fmt.Errorf("import-koinly: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := parseRow(cols, rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-koinly: %s: %v", err, rec)
+		}
+
+		if tx == nil {
+			continue
+		}
+
+		// TxHash is not unique, e.g. for trades with multiple
+		// received currencies, the ID is derived from the content
+		tx.ID = ids.ID(rec)
+		tx.Exchange = ExchangeName
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+// parseRow returns the transaction of a row, nil is returned for fiat
+// deposits and withdrawals.
+func parseRow(cols csvfile.Columns, rec []string) (*transaction.Tx, error) {
+	var row universal.Row
+	var err error
+
+	row.Timestamp, err = csvfile.ParseTime(cols.Get(rec, "Date"), time.UTC, timeFormats...)
+	if err != nil {
+		return nil, err
+	}
+
+	row.Sent, row.SentCurrency, err = parseAmount(cols.Get(rec, "Sent Amount"), cols.Get(rec, "Sent Currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	row.Received, row.ReceivedCurrency, err = parseAmount(cols.Get(rec, "Received Amount"), cols.Get(rec, "Received Currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	row.Fee, row.FeeCurrency, err = parseAmount(cols.Get(rec, "Fee Amount"), cols.Get(rec, "Fee Currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	row.Value, row.ValueCurrency, err = parseAmount(cols.Get(rec, "Net Worth Amount"), cols.Get(rec, "Net Worth Currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	label := strings.ToLower(cols.Get(rec, "Label"))
+
+	switch {
+	case row.IsTrade():
+		return row.Trade()
+
+	case row.IsFiatTransfer():
+		log.Debugf("import-koinly: skipping fiat transfer: %v", rec)
+		return nil, nil
+
+	case row.ReceivedCurrency != transaction.CurrencyUndef:
+		txType, exist := incomingLabels[label]
+		if !exist {
+			return nil, fmt.Errorf("unsupported label %q of received amount", label)
+		}
+
+		return row.Incoming(txType)
+
+	case row.SentCurrency != transaction.CurrencyUndef:
+		txType, exist := outgoingLabels[label]
+		if !exist {
+			return nil, fmt.Errorf("unsupported label %q of sent amount", label)
+		}
+
+		return row.Outgoing(txType)
+	}
+
+	return nil, errors.New("sent and received currency are missing")
+}
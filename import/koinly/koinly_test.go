@@ -0,0 +1,42 @@
+package koinly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/koinly.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dot := importtest.Currency(t, "DOT")
+
+	// the fiat deposit is skipped
+	importtest.Check(t, res, []importtest.Tx{
+		{
+			Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+			Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.01",
+			PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "1.5", FeeCurrency: transaction.EUR,
+		},
+		{Type: transaction.Income, Currency: dot, Quantity: "5", PayCurrency: transaction.EUR, SpotPrice: "20"},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.001", PayCurrency: transaction.EUR, SpotPrice: "40000"},
+		{Type: transaction.Lost, Currency: transaction.BTC, Quantity: "0.001"},
+		// trades between cryptocurrencies are sells of the sent currency
+		{Type: transaction.Sell, Currency: dot, Quantity: "2", PayCurrency: transaction.ETH, SpotPrice: "0.001"},
+		{Type: transaction.Withdrawal, Currency: transaction.BTC, Quantity: "0.001", Fees: "0.0001", FeeCurrency: transaction.BTC},
+		{Type: transaction.GiftReceived, Currency: transaction.ETH, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "2000"},
+	})
+
+	for _, tx := range res {
+		if tx.Exchange != ExchangeName || len(tx.ID) == 0 {
+			t.Errorf("transaction %s has exchange %q and ID %q", tx, tx.Exchange, tx.ID)
+		}
+	}
+}
@@ -5,10 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"time"
 
+	"github.com/fho/cryptotax/log"
 	"github.com/fho/cryptotax/math"
 	"github.com/fho/cryptotax/transaction"
 )
@@ -40,6 +40,10 @@ func parseCurrency(v string) (from transaction.Currency, to transaction.Currency
 	//format: https://support.kraken.com/hc/en-us/articles/360001185506-Asset-Codes
 	/// XBTLTC, XXLMXXBT, XXRPBCH
 
+	if len(v) < 6 {
+		return 0, 0, errors.New("currency pair is too short")
+	}
+
 	str := v[:3]
 	from, exist = currencies[str]
 	if !exist {
@@ -86,6 +90,9 @@ func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
 		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("import-kraken: %w", err)
+		}
 
 		id := rec[0]
 
@@ -107,28 +114,28 @@ func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
 			return nil, fmt.Errorf("parsing %q failed: %s", rec[4], err)
 		}
 
-		spotPrice, success := math.NewFloat().SetString(rec[6])
-		if !success {
-			log.Fatalf("import-kraken: converting %q to big float failed", rec[6])
+		spotPrice, err := math.ParseDecimal(rec[6])
+		if err != nil {
+			return nil, fmt.Errorf("import-kraken: converting %q to decimal failed: %s", rec[6], err)
 		}
 
-		var fee = math.NewFloat()
+		var fee math.Decimal
 		if paycurrency != transaction.EUR {
 			/* kraken fees are in the paycurrency, we can't handle
 			* them correct when calculating the fees because we
 			* don't know how much the currency was worth in eur when the
 			* fees were paid */
-			log.Printf("import-kraken: WARN: currency was not bought in euro, fees are ignored: %+v\n", rec)
+			log.Warnf("import-kraken: currency was not bought in euro, fees are ignored: %+v", rec)
 		} else {
-			fee, success = math.NewFloat().SetString(rec[8])
-			if !success {
-				log.Fatalf("import-kraken: converting %q to big float failed", rec[8])
+			fee, err = math.ParseDecimal(rec[8])
+			if err != nil {
+				return nil, fmt.Errorf("import-kraken: converting %q to decimal failed: %s", rec[8], err)
 			}
 		}
 
-		quantity, success := math.NewFloat().SetString(rec[9])
-		if !success {
-			log.Fatalf("import-kraken: converting %q to big float failed", rec[9])
+		quantity, err := math.ParseDecimal(rec[9])
+		if err != nil {
+			return nil, fmt.Errorf("import-kraken: converting %q to decimal failed: %s", rec[9], err)
 		}
 
 		txRec := transaction.Tx{
@@ -0,0 +1,238 @@
+// Package kucoin imports the spot trade history CSV exports of KuCoin.
+package kucoin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/import/rowid"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+const ExchangeName = "KuCoin"
+
+type Import struct{}
+
+/* csv formats:
+
+legacy trade history:
+tradeCreatedAt,orderId,symbol,side,price,size,funds,fee,liquidity,feeCurrency,orderType
+2021-01-05 10:00:00,60a1b2c3,BTC-USDT,buy,30000,0.01,300,0.3,taker,USDT,limit
+
+trade history since 2023, the time zone is part of the time column name:
+UID,Account Type,Order ID,Symbol,Side,Order Type,Avg. Filled Price,Filled Amount,Filled Volume,Filled Volume (USDT),Filled Time(UTC+02:00),Fee,Maker/Taker,Fee Currency
+1234,mainAccount,60a1b2c3,BTC-USDT,BUY,LIMIT,30000,0.01,300,300,2021-01-05 12:00:00,0.3,TAKER,USDT
+*/
+
+// column contains the names of a column in the supported formats.
+type column []string
+
+var (
+	colSymbol      = column{"symbol", "Symbol"}
+	colSide        = column{"side", "Side"}
+	colPrice       = column{"price", "Avg. Filled Price"}
+	colSize        = column{"size", "Filled Amount"}
+	colFee         = column{"fee", "Fee"}
+	colFeeCurrency = column{"feeCurrency", "Fee Currency"}
+)
+
+const (
+	legacyTimeColumn = "tradeCreatedAt"
+	timeColumnPrefix = "Filled Time(UTC"
+	timeLayout       = "2006-01-02 15:04:05"
+)
+
+// format are the column indices of an export.
+type format struct {
+	time, symbol, side, price, size, fee, feeCurrency int
+	location                                          *time.Location
+}
+
+// parseHeader returns the format of the export with the header rec.
+func parseHeader(rec []string) (*format, error) {
+	var f format
+	var exist bool
+
+	cols := csvfile.ParseHeader(rec)
+	for name, i := range cols {
+		if strings.HasPrefix(name, timeColumnPrefix) {
+			loc, err := parseLocation(strings.TrimSuffix(strings.TrimPrefix(name, timeColumnPrefix), ")"))
+			if err != nil {
+				return nil, fmt.Errorf("parsing time zone of column %q failed: %s", name, err)
+			}
+
+			f.time = i
+			f.location = loc
+		}
+	}
+
+	if f.location == nil {
+		f.time, exist = cols[legacyTimeColumn]
+		if !exist {
+			return nil, errors.New("time column is missing")
+		}
+		f.location = time.UTC
+	}
+
+	for _, c := range []struct {
+		col column
+		idx *int
+	}{
+		{colSymbol, &f.symbol},
+		{colSide, &f.side},
+		{colPrice, &f.price},
+		{colSize, &f.size},
+		{colFee, &f.fee},
+		{colFeeCurrency, &f.feeCurrency},
+	} {
+		*c.idx, exist = cols.Index(c.col...)
+		if !exist {
+			return nil, fmt.Errorf("column %q is missing", c.col[0])
+		}
+	}
+
+	return &f, nil
+}
+
+// parseLocation parses an UTC offset, e.g. +02:00. An empty offset is UTC.
+func parseLocation(v string) (*time.Location, error) {
+	if len(v) == 0 {
+		return time.UTC, nil
+	}
+
+	ts, err := time.Parse("-07:00", v)
+	if err != nil {
+		return nil, err
+	}
+
+	_, offset := ts.Zone()
+
+	return time.FixedZone("UTC"+v, offset), nil
+}
+
+// parseSymbol parses a trading pair, e.g. BTC-USDT.
+func parseSymbol(v string) (base, quote transaction.Currency, err error) {
+	fields := strings.Split(v, "-")
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("parsing symbol %q failed: expected format BASE-QUOTE", v)
+	}
+
+	base, err = transaction.RegisterCurrency(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing %q failed: %s", fields[0], err)
+	}
+
+	quote, err = transaction.RegisterCurrency(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing %q failed: %s", fields[1], err)
+	}
+
+	return base, quote, nil
+}
+
+func (p *Import) FromCSV(path string) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+	var ids rowid.Generator
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := parseHeader(rec)
+	if err != nil {
+		return nil, fmt.Errorf("import-kucoin: %s", err)
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := format.parseTrade(rec)
+		if err != nil {
+			return nil, fmt.Errorf("import-kucoin: %s: %v", err, rec)
+		}
+
+		// the order ids are not unique, orders can have multiple
+		// fills, the ID is derived from the content
+		tx.ID = ids.ID(rec)
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
+
+func (f *format) parseTrade(rec []string) (*transaction.Tx, error) {
+	base, quote, err := parseSymbol(rec[f.symbol])
+	if err != nil {
+		return nil, err
+	}
+
+	var txType transaction.Type
+	switch strings.ToLower(rec[f.side]) {
+	case "buy":
+		txType = transaction.Buy
+	case "sell":
+		txType = transaction.Sell
+	default:
+		return nil, fmt.Errorf("unsupported side %q", rec[f.side])
+	}
+
+	ts, err := time.ParseInLocation(timeLayout, rec[f.time], f.location)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[f.time], err)
+	}
+
+	price, err := math.ParseDecimal(rec[f.price])
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := math.ParseDecimal(rec[f.size])
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := math.ParseDecimal(rec[f.fee])
+	if err != nil {
+		return nil, err
+	}
+
+	feeCurrency, err := transaction.RegisterCurrency(rec[f.feeCurrency])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %s", rec[f.feeCurrency], err)
+	}
+
+	return &transaction.Tx{
+		Exchange:    ExchangeName,
+		Timestamp:   ts.UTC(),
+		Type:        txType,
+		PayCurrency: quote,
+		Currency:    base,
+		Quantity:    size,
+		SpotPrice:   price,
+		Fees:        fee,
+		FeeCurrency: feeCurrency,
+	}, nil
+}
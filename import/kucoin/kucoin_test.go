@@ -0,0 +1,80 @@
+package kucoin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	// USDT is only registered by the import
+	_, err := p.FromCSV("testdata/trade-history-legacy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usdt := importtest.Currency(t, "USDT")
+
+	tests := []struct {
+		path string
+		want []importtest.Tx
+	}{
+		{
+			path: "testdata/trade-history-legacy.csv",
+			want: []importtest.Tx{
+				{
+					Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+					Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: usdt, SpotPrice: "30000", Fees: "0.3", FeeCurrency: usdt,
+				},
+				// the same fill twice
+				{Type: transaction.Buy, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: usdt, SpotPrice: "30000", Fees: "0.3", FeeCurrency: usdt},
+				{Type: transaction.Sell, Currency: transaction.ETH, Quantity: "0.5", PayCurrency: transaction.BTC, SpotPrice: "0.04", Fees: "0.00002", FeeCurrency: transaction.BTC},
+			},
+		},
+		{
+			path: "testdata/trade-history.csv",
+			want: []importtest.Tx{
+				// the time column is in UTC+02:00
+				{
+					Timestamp: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+					Type:      transaction.Buy, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "0.3", FeeCurrency: transaction.EUR,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res, err := p.FromCSV(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			importtest.Check(t, res, tt.want)
+		})
+	}
+}
+
+func TestIdenticalFillsHaveDifferentIDs(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/trade-history-legacy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res[0].ID == res[1].ID {
+		t.Errorf("identical fills have the same ID %q", res[0].ID)
+	}
+}
+
+func TestParseHeaderMissingColumn(t *testing.T) {
+	_, err := parseHeader([]string{"tradeCreatedAt", "symbol", "side", "price", "size", "fee"})
+	if err == nil {
+		t.Error("parsing a header without fee currency column succeeded")
+	}
+}
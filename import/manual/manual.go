@@ -0,0 +1,377 @@
+// Package manual reads transactions and corrections that are maintained by
+// hand in a JSON file:
+//
+//	{
+//	  "currencies": ["ARB"],
+//	  "transactions": [
+//	    {
+//	      "id": "otc-1",
+//	      "exchange": "OTC Desk",
+//	      "wallet": "ledger",
+//	      "timestamp": "2021-03-01T12:00:00Z",
+//	      "type": "buy",
+//	      "currency": "BTC",
+//	      "pay_currency": "EUR",
+//	      "quantity": "0.5",
+//	      "spot_price": "41000",
+//	      "fees": "20"
+//	    },
+//	    {
+//	      "id": "otc-2",
+//	      "timestamp": "2021-04-01T12:00:00Z",
+//	      "type": "sell",
+//	      "currency": "BTC",
+//	      "pay_currency": "ETH",
+//	      "quantity": "0.1",
+//	      "spot_price": "16",
+//	      "value": "5500"
+//	    },
+//	    {
+//	      "id": "gift-1",
+//	      "timestamp": "2021-05-01T12:00:00Z",
+//	      "type": "gift-received",
+//	      "currency": "BTC",
+//	      "quantity": "0.2",
+//	      "spot_price": "45000",
+//	      "donor_acquired": "2019-06-01T12:00:00Z",
+//	      "donor_cost": "1500"
+//	    }
+//	  ],
+//	  "overrides": [
+//	    {"id": "TXID-1", "exchange": "Kraken", "spot_price": "41250.5"},
+//	    {"id": "TXID-2", "ignore": true},
+//	    {"id": "TXID-3", "type": "withdrawal"},
+//	    {"id": "TXID-4", "donor_acquired": "2020-01-01T00:00:00Z", "donor_cost": "300"}
+//	  ]
+//	}
+//
+// Supported transaction types are: buy, sell, deposit, withdrawal, income,
+// gift-received, gift-sent and lost. spot_price is the price per unit in
+// pay_currency, fees are in fee_currency or if it is not set in
+// pay_currency. Fees in cryptocurrencies are removed from the holdings.
+// value is the € market value of trades between cryptocurrencies, the
+// profit of the sold currency is realized at it. quantity must be positive,
+// spot_price, fees and value must not be negative and the spot_price of
+// buys and sells must be set. Transactions with the same exchange, wallet
+// and id as an imported transaction are dropped as duplicates.
+//
+// A received gift takes over the acquisition date and the € cost basis of
+// the donor, they are set with donor_acquired and donor_cost. Without them
+// the gift is accounted at its market value at the receipt and a warning
+// is logged.
+//
+// Currencies that do not occur in imported files have to be declared in
+// currencies, other unknown currencies are rejected to detect typos.
+//
+// Overrides modify imported transactions, they are identified by their
+// id and optionally their exchange. spot_price, fees, value, type,
+// donor_acquired and donor_cost replace the values of the transaction,
+// ignore removes it.
+package manual
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// ExchangeName is the exchange of manual transactions that do not specify
+// one.
+const ExchangeName = "Manual"
+
+type Import struct{}
+
+// Transaction is a manually recorded transaction.
+type Transaction struct {
+	ID          string       `json:"id"`
+	Exchange    string       `json:"exchange"`
+	Wallet      string       `json:"wallet"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Type        string       `json:"type"`
+	Currency    string       `json:"currency"`
+	PayCurrency string       `json:"pay_currency"`
+	Quantity    math.Decimal `json:"quantity"`
+	SpotPrice   math.Decimal `json:"spot_price"`
+	Fees        math.Decimal `json:"fees"`
+	FeeCurrency string       `json:"fee_currency"`
+	Value       math.Decimal `json:"value"`
+	// DonorAcquired and DonorCost are the acquisition date and cost
+	// basis of a received gift at its donor
+	DonorAcquired time.Time    `json:"donor_acquired"`
+	DonorCost     math.Decimal `json:"donor_cost"`
+}
+
+// Override modifies an imported transaction.
+type Override struct {
+	ID        string        `json:"id"`
+	Exchange  string        `json:"exchange"`
+	Ignore    bool          `json:"ignore"`
+	Type      string        `json:"type"`
+	SpotPrice *math.Decimal `json:"spot_price"`
+	Fees      *math.Decimal `json:"fees"`
+	Value     *math.Decimal `json:"value"`
+
+	DonorAcquired *time.Time    `json:"donor_acquired"`
+	DonorCost     *math.Decimal `json:"donor_cost"`
+}
+
+// File is the content of a manual transactions file.
+type File struct {
+	// Currencies are the symbols of new currencies that are used by
+	// the transactions
+	Currencies   []string       `json:"currencies"`
+	Transactions []*Transaction `json:"transactions"`
+	Overrides    []*Override    `json:"overrides"`
+}
+
+func (p *Import) FromJSON(path string) (*File, error) {
+	var res File
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %s", path, err)
+	}
+
+	for _, symbol := range res.Currencies {
+		_, err := transaction.RegisterCurrency(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%s: registering currency %q failed: %s", path, symbol, err)
+		}
+	}
+
+	return &res, nil
+}
+
+func (t *Transaction) toTx() (*transaction.Tx, error) {
+	if len(t.ID) == 0 {
+		return nil, fmt.Errorf("transaction at %s has no id", t.Timestamp)
+	}
+
+	if t.Timestamp.IsZero() {
+		return nil, fmt.Errorf("transaction %s has no timestamp", t.ID)
+	}
+
+	txType, err := transaction.NewType(t.Type)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s: parsing %q failed: %s", t.ID, t.Type, err)
+	}
+
+	currency, err := transaction.NewCurrency(t.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s: parsing %q failed: %s", t.ID, t.Currency, err)
+	}
+
+	payCurrency := transaction.EUR
+	if len(t.PayCurrency) != 0 {
+		payCurrency, err = transaction.NewCurrency(t.PayCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: parsing %q failed: %s", t.ID, t.PayCurrency, err)
+		}
+	}
+
+	var feeCurrency transaction.Currency
+	if len(t.FeeCurrency) != 0 {
+		feeCurrency, err = transaction.NewCurrency(t.FeeCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: parsing %q failed: %s", t.ID, t.FeeCurrency, err)
+		}
+	}
+
+	if t.Quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("transaction %s: quantity must be positive", t.ID)
+	}
+
+	if t.SpotPrice.Sign() < 0 || t.Fees.Sign() < 0 || t.Value.Sign() < 0 {
+		return nil, fmt.Errorf("transaction %s: spot_price, fees and value must not be negative", t.ID)
+	}
+
+	if (txType == transaction.Buy || txType == transaction.Sell) && t.SpotPrice.Sign() == 0 {
+		return nil, fmt.Errorf("transaction %s: %s has no spot_price", t.ID, txType)
+	}
+
+	exchange := t.Exchange
+	if len(exchange) == 0 {
+		exchange = ExchangeName
+	}
+
+	tx := transaction.Tx{
+		ID:          t.ID,
+		Exchange:    exchange,
+		Wallet:      t.Wallet,
+		Timestamp:   t.Timestamp,
+		Type:        txType,
+		PayCurrency: payCurrency,
+		Currency:    currency,
+		Quantity:    t.Quantity,
+		SpotPrice:   t.SpotPrice,
+		Fees:        t.Fees,
+		FeeCurrency: feeCurrency,
+		Value:       t.Value,
+
+		DonorAcquired: t.DonorAcquired,
+		DonorCost:     t.DonorCost,
+	}
+
+	err = checkDonor(&tx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s: %s", t.ID, err)
+	}
+
+	return &tx, nil
+}
+
+// checkDonor returns an error if the acquisition date and cost of the
+// donor of tx are invalid.
+func checkDonor(tx *transaction.Tx) error {
+	if tx.DonorAcquired.IsZero() {
+		if !tx.DonorCost.IsZero() {
+			return errors.New("donor_cost is set without donor_acquired")
+		}
+
+		return nil
+	}
+
+	if tx.Type != transaction.GiftReceived {
+		return fmt.Errorf("donor_acquired is set for a transaction of type %s, only gifts have a donor", tx.Type)
+	}
+
+	if tx.DonorAcquired.After(tx.Timestamp) {
+		return fmt.Errorf("donor_acquired %s is after the receipt of the gift", tx.DonorAcquired)
+	}
+
+	if tx.DonorCost.Sign() < 0 {
+		return errors.New("donor_cost must not be negative")
+	}
+
+	return nil
+}
+
+// validate returns an error if a value of the override is negative.
+func (o *Override) validate() error {
+	if o.SpotPrice != nil && o.SpotPrice.Sign() < 0 {
+		return fmt.Errorf("override %s: spot_price must not be negative", o.ID)
+	}
+
+	if o.Fees != nil && o.Fees.Sign() < 0 {
+		return fmt.Errorf("override %s: fees must not be negative", o.ID)
+	}
+
+	if o.Value != nil && o.Value.Sign() < 0 {
+		return fmt.Errorf("override %s: value must not be negative", o.ID)
+	}
+
+	return nil
+}
+
+func (o *Override) matches(tx *transaction.Tx) bool {
+	if o.ID != tx.ID {
+		return false
+	}
+
+	return len(o.Exchange) == 0 || o.Exchange == tx.Exchange
+}
+
+// Apply applies the overrides to records and appends the manual
+// transactions. records are not modified, changed transactions are
+// copied.
+// An error is returned if an override does not match any transaction.
+func (f *File) Apply(records []*transaction.Tx) ([]*transaction.Tx, error) {
+	var results []*transaction.Tx
+
+	used := make([]bool, len(f.Overrides))
+
+	for _, o := range f.Overrides {
+		err := o.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rec := range records {
+		tx := rec
+		ignore := false
+
+		for i, o := range f.Overrides {
+			if !o.matches(rec) {
+				continue
+			}
+			used[i] = true
+
+			if o.Ignore {
+				ignore = true
+				continue
+			}
+
+			if tx == rec {
+				txCp := *rec
+				tx = &txCp
+			}
+
+			if len(o.Type) != 0 {
+				txType, err := transaction.NewType(o.Type)
+				if err != nil {
+					return nil, fmt.Errorf("override %s: parsing %q failed: %s", o.ID, o.Type, err)
+				}
+				tx.Type = txType
+			}
+
+			if o.SpotPrice != nil {
+				tx.SpotPrice = *o.SpotPrice
+			}
+
+			if o.Fees != nil {
+				tx.Fees = *o.Fees
+			}
+
+			if o.Value != nil {
+				tx.Value = *o.Value
+			}
+
+			if o.DonorAcquired != nil {
+				tx.DonorAcquired = *o.DonorAcquired
+			}
+
+			if o.DonorCost != nil {
+				tx.DonorCost = *o.DonorCost
+			}
+		}
+
+		if tx != rec {
+			err := checkDonor(tx)
+			if err != nil {
+				return nil, fmt.Errorf("overrides of %s: %s", tx.ID, err)
+			}
+		}
+
+		if !ignore {
+			results = append(results, tx)
+		}
+	}
+
+	for i, o := range f.Overrides {
+		if !used[i] {
+			return nil, fmt.Errorf("override %s: no transaction with this id exists", o.ID)
+		}
+	}
+
+	for _, t := range f.Transactions {
+		tx, err := t.toTx()
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, tx)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,140 @@
+package manual
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func validGift() *Transaction {
+	return &Transaction{
+		ID:            "gift-1",
+		Timestamp:     time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC),
+		Type:          "gift-received",
+		Currency:      "BTC",
+		Quantity:      math.MustParseDecimal("0.2"),
+		SpotPrice:     math.MustParseDecimal("45000"),
+		DonorAcquired: time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC),
+		DonorCost:     math.MustParseDecimal("1500"),
+	}
+}
+
+func TestToTx(t *testing.T) {
+	tx, err := validGift().toTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !tx.DonorAcquired.Equal(validGift().DonorAcquired) || tx.DonorCost.Cmp(validGift().DonorCost) != 0 {
+		t.Errorf("acquisition of the donor was not taken over: %s, %s", tx.DonorAcquired, tx.DonorCost)
+	}
+}
+
+func TestToTxInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Transaction)
+	}{
+		{name: "zero quantity", modify: func(t *Transaction) { t.Quantity = math.Decimal{} }},
+		{name: "negative quantity", modify: func(t *Transaction) { t.Quantity = math.MustParseDecimal("-1") }},
+		{name: "negative spot price", modify: func(t *Transaction) { t.SpotPrice = math.MustParseDecimal("-1") }},
+		{name: "negative fees", modify: func(t *Transaction) { t.Fees = math.MustParseDecimal("-1") }},
+		{name: "buy without spot price", modify: func(t *Transaction) {
+			t.Type = "buy"
+			t.SpotPrice = math.Decimal{}
+			t.DonorAcquired = time.Time{}
+			t.DonorCost = math.Decimal{}
+		}},
+		{name: "donor of an income", modify: func(t *Transaction) { t.Type = "income" }},
+		{name: "donor acquisition after the receipt", modify: func(t *Transaction) { t.DonorAcquired = t.Timestamp.Add(time.Hour) }},
+		{name: "negative donor cost", modify: func(t *Transaction) { t.DonorCost = math.MustParseDecimal("-1") }},
+		{name: "donor cost without acquisition", modify: func(t *Transaction) { t.DonorAcquired = time.Time{} }},
+	}
+
+	for _, tt := range tests {
+		tr := validGift()
+		tt.modify(tr)
+
+		if _, err := tr.toTx(); err == nil {
+			t.Errorf("converting a transaction with %s succeeded", tt.name)
+		}
+	}
+}
+
+func imported() []*transaction.Tx {
+	return []*transaction.Tx{{
+		ID:          "TXID-1",
+		Exchange:    "Kraken",
+		Timestamp:   time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Type:        transaction.Buy,
+		Currency:    transaction.BTC,
+		PayCurrency: transaction.EUR,
+		Quantity:    math.MustParseDecimal("0.5"),
+		SpotPrice:   math.MustParseDecimal("41000"),
+	}}
+}
+
+func decimal(s string) *math.Decimal {
+	d := math.MustParseDecimal(s)
+	return &d
+}
+
+func TestApplyOverrides(t *testing.T) {
+	records := imported()
+	donorAcquired := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	f := File{Overrides: []*Override{
+		{ID: "TXID-1", Exchange: "Kraken", SpotPrice: decimal("41250.5"), Fees: decimal("10")},
+		{ID: "TXID-1", Type: "gift-received", DonorAcquired: &donorAcquired, DonorCost: decimal("300")},
+	}}
+
+	res, err := f.Apply(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 1 {
+		t.Fatalf("got %d transactions, want 1", len(res))
+	}
+
+	tx := res[0]
+	if tx.Type != transaction.GiftReceived || tx.SpotPrice.Cmp(*decimal("41250.5")) != 0 || tx.Fees.Cmp(*decimal("10")) != 0 {
+		t.Errorf("overrides were not applied: %s", tx)
+	}
+
+	if !tx.DonorAcquired.Equal(donorAcquired) || tx.DonorCost.Cmp(*decimal("300")) != 0 {
+		t.Errorf("acquisition of the donor was not applied: %s, %s", tx.DonorAcquired, tx.DonorCost)
+	}
+
+	if records[0].Type != transaction.Buy {
+		t.Error("the imported transaction was modified")
+	}
+}
+
+func TestApplyInvalidOverrides(t *testing.T) {
+	donorAcquired := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		override Override
+	}{
+		{name: "negative spot price", override: Override{ID: "TXID-1", SpotPrice: decimal("-1")}},
+		{name: "negative fees", override: Override{ID: "TXID-1", Fees: decimal("-1")}},
+		{name: "negative value", override: Override{ID: "TXID-1", Value: decimal("-1")}},
+		{name: "unknown type", override: Override{ID: "TXID-1", Type: "swap"}},
+		{name: "unknown id", override: Override{ID: "TXID-2", Ignore: true}},
+		{name: "donor of a buy", override: Override{ID: "TXID-1", DonorAcquired: &donorAcquired}},
+		{name: "negative donor cost", override: Override{ID: "TXID-1", Type: "gift-received", DonorAcquired: &donorAcquired, DonorCost: decimal("-1")}},
+	}
+
+	for _, tt := range tests {
+		o := tt.override
+		f := File{Overrides: []*Override{&o}}
+
+		if _, err := f.Apply(imported()); err == nil {
+			t.Errorf("applying an override with %s succeeded", tt.name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package openingbalance reads holdings that were acquired before the
+// imported transaction histories.
+//
+// The CSV file must start with the header line:
+//
+//	currency,quantity,acquired,cost,wallet
+//
+// acquired is the date (2006-01-02) or time (RFC3339) of the acquisition,
+// cost the cost basis in € of the whole quantity, wallet is optional.
+package openingbalance
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/accounting"
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+type Import struct{}
+
+var header = []string{"currency", "quantity", "acquired", "cost", "wallet"}
+
+// timeFormats of the dates, days are in the local time zone
+var timeFormats = []string{time.RFC3339, "2006-01-02"}
+
+func (p *Import) FromCSV(path string) ([]*accounting.OpeningBalance, error) {
+	var results []*accounting.OpeningBalance
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = len(header)
+	csvReader.TrimLeadingSpace = true
+
+	rec, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csvfile.CheckHeader(rec, header); err != nil {
+		return nil, fmt.Errorf("%s: %s, expecting: %s", path, err, strings.Join(header, ","))
+	}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		currency, err := transaction.NewCurrency(rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %s", rec[0], err)
+		}
+
+		quantity, err := math.ParseDecimal(rec[1])
+		if err != nil {
+			return nil, fmt.Errorf("parsing quantity failed: %s", err)
+		}
+
+		if quantity.Sign() <= 0 {
+			return nil, fmt.Errorf("quantity %s of %s is not positive", quantity, currency)
+		}
+
+		acquired, err := csvfile.ParseTime(rec[2], time.Local, timeFormats...)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %s", rec[2], err)
+		}
+
+		cost, err := math.ParseDecimal(rec[3])
+		if err != nil {
+			return nil, fmt.Errorf("parsing cost failed: %s", err)
+		}
+
+		results = append(results, &accounting.OpeningBalance{
+			Currency: currency,
+			Quantity: quantity,
+			Acquired: acquired,
+			Cost:     cost,
+			Wallet:   rec[4],
+		})
+	}
+
+	return results, nil
+}
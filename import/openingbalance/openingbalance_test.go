@@ -0,0 +1,66 @@
+package openingbalance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestFromCSV(t *testing.T) {
+	var p Import
+
+	res, err := p.FromCSV("testdata/opening-balances.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 {
+		t.Fatalf("got %d opening balances, want 2", len(res))
+	}
+
+	tests := []struct {
+		currency transaction.Currency
+		quantity string
+		acquired time.Time
+		cost     string
+		wallet   string
+	}{
+		{transaction.BTC, "0.5", time.Date(2016, 5, 1, 0, 0, 0, 0, time.Local), "210.50", "ledger"},
+		{transaction.ETH, "2", time.Date(2017, 1, 2, 10, 0, 0, 0, time.UTC), "16", ""},
+	}
+
+	for i, tt := range tests {
+		ob := res[i]
+
+		if ob.Currency != tt.currency || ob.Quantity.Cmp(math.MustParseDecimal(tt.quantity)) != 0 ||
+			!ob.Acquired.Equal(tt.acquired) || ob.Cost.Cmp(math.MustParseDecimal(tt.cost)) != 0 || ob.Wallet != tt.wallet {
+			t.Errorf("opening balance %d is %+v, want %+v", i, ob, tt)
+		}
+	}
+}
+
+func TestFromCSVInvalid(t *testing.T) {
+	tests := map[string]string{
+		"wrong header":      "currency,amount,acquired,cost,wallet\n",
+		"unknown currency":  "currency,quantity,acquired,cost,wallet\nNOPE,1,2016-05-01,1,\n",
+		"negative quantity": "currency,quantity,acquired,cost,wallet\nBTC,-1,2016-05-01,1,\n",
+		"invalid date":      "currency,quantity,acquired,cost,wallet\nBTC,1,01.05.2016,1,\n",
+	}
+
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "ob.csv")
+		err := os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var p Import
+		if _, err := p.FromCSV(path); err == nil {
+			t.Errorf("importing a file with %s succeeded", name)
+		}
+	}
+}
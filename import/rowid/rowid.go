@@ -0,0 +1,33 @@
+// Package rowid derives transaction IDs from the content of CSV rows, for
+// exports that do not contain IDs.
+package rowid
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Generator returns IDs for the rows of a file. Identical rows are
+// numbered by their occurrence in the file to distinguish them.
+type Generator struct {
+	occurrences map[string]int
+}
+
+// ID returns the ID of a CSV row.
+func (g *Generator) ID(rec []string) string {
+	if g.occurrences == nil {
+		g.occurrences = map[string]int{}
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(rec, "\x00")))
+	id := hex.EncodeToString(sum[:16])
+
+	g.occurrences[id]++
+	if n := g.occurrences[id]; n > 1 {
+		return fmt.Sprintf("%s-%d", id, n)
+	}
+
+	return id
+}
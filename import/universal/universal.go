@@ -0,0 +1,170 @@
+// Package universal converts the rows of portfolio tracker exports, that
+// describe transactions by their sent and received amounts, to
+// transactions.
+package universal
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+// Row is a transaction of a portfolio tracker export.
+type Row struct {
+	Timestamp        time.Time
+	Sent             math.Decimal
+	SentCurrency     transaction.Currency
+	Received         math.Decimal
+	ReceivedCurrency transaction.Currency
+	Fee              math.Decimal
+	FeeCurrency      transaction.Currency
+	// Value is the market value of the transaction
+	Value         math.Decimal
+	ValueCurrency transaction.Currency
+}
+
+// IsTrade returns true if an amount was sent and received.
+func (r *Row) IsTrade() bool {
+	return r.SentCurrency != transaction.CurrencyUndef && r.ReceivedCurrency != transaction.CurrencyUndef
+}
+
+// IsFiatTransfer returns true if only a fiat currency was sent or
+// received.
+func (r *Row) IsFiatTransfer() bool {
+	if r.IsTrade() {
+		return false
+	}
+
+	return r.SentCurrency.IsFiat() || r.ReceivedCurrency.IsFiat()
+}
+
+func (r *Row) tx(txType transaction.Type, currency transaction.Currency, quantity math.Decimal) *transaction.Tx {
+	tx := transaction.Tx{
+		Timestamp: r.Timestamp,
+		Type:      txType,
+		Currency:  currency,
+		Quantity:  quantity,
+	}
+
+	if !r.Fee.IsZero() {
+		tx.Fees = r.Fee
+		tx.FeeCurrency = r.FeeCurrency
+	}
+
+	return &tx
+}
+
+// valued sets the price of tx to its market value.
+func (r *Row) valued(tx *transaction.Tx) {
+	if r.Value.IsZero() {
+		log.Warnf("import: %s of %s %s at %s has no market value, it is valued with 0€",
+			tx.Type, tx.Quantity, tx.Currency, tx.Timestamp.Format(time.RFC3339))
+	}
+
+	tx.PayCurrency = r.ValueCurrency
+	if tx.PayCurrency == transaction.CurrencyUndef {
+		tx.PayCurrency = transaction.EUR
+	}
+
+	if !tx.Quantity.IsZero() {
+		tx.SpotPrice = r.Value.Quo(tx.Quantity, math.DivScale, math.RoundHalfEven).Normalize()
+	}
+}
+
+// Trade returns the exchange of the sent for the received currency. If one
+// of both is a fiat currency it is a buy or sell. Otherwise it is booked as
+// sell of the sent currency that is paid with the received one, its value
+// is the market value if it is in €.
+func (r *Row) Trade() (*transaction.Tx, error) {
+	if !r.IsTrade() {
+		return nil, errors.New("sent or received currency is missing")
+	}
+
+	if r.Sent.IsZero() || r.Received.IsZero() {
+		return nil, errors.New("sent or received amount is 0")
+	}
+
+	if r.SentCurrency.IsFiat() {
+		tx := r.tx(transaction.Buy, r.ReceivedCurrency, r.Received)
+		tx.PayCurrency = r.SentCurrency
+		tx.SpotPrice = r.Sent.Quo(r.Received, math.DivScale, math.RoundHalfEven).Normalize()
+
+		return tx, nil
+	}
+
+	tx := r.tx(transaction.Sell, r.SentCurrency, r.Sent)
+	tx.PayCurrency = r.ReceivedCurrency
+
+	if r.ReceivedCurrency.IsFiat() {
+		tx.SpotPrice = r.Received.Quo(r.Sent, math.DivScale, math.RoundHalfEven).Normalize()
+	} else {
+		// rounded up, a rounding remainder stays in the received lot
+		// instead of missing when it is sold
+		tx.SpotPrice = r.Received.Quo(r.Sent, math.DivScale, math.RoundUp).Normalize()
+
+		if r.ValueCurrency == transaction.EUR {
+			tx.Value = r.Value
+		}
+	}
+
+	return tx, nil
+}
+
+// Incoming returns the receipt of a currency, txType is Deposit, Income or
+// GiftReceived. Income and gifts are valued at the market value.
+func (r *Row) Incoming(txType transaction.Type) (*transaction.Tx, error) {
+	if r.ReceivedCurrency == transaction.CurrencyUndef {
+		return nil, fmt.Errorf("received currency of %s is missing", txType)
+	}
+
+	tx := r.tx(txType, r.ReceivedCurrency, r.Received)
+
+	switch txType {
+	case transaction.Deposit:
+	case transaction.Income, transaction.GiftReceived:
+		r.valued(tx)
+	default:
+		return nil, fmt.Errorf("%s is not an incoming transaction", txType)
+	}
+
+	return tx, nil
+}
+
+// NonTaxable returns the receipt of a currency that is not taxed as income,
+// e.g. a non-taxable airdrop. It is booked as income with a cost basis of
+// 0€ instead of its market value.
+func (r *Row) NonTaxable() (*transaction.Tx, error) {
+	if r.ReceivedCurrency == transaction.CurrencyUndef {
+		return nil, errors.New("received currency of non-taxable income is missing")
+	}
+
+	tx := r.tx(transaction.Income, r.ReceivedCurrency, r.Received)
+	tx.PayCurrency = transaction.EUR
+
+	return tx, nil
+}
+
+// Outgoing returns the disposal of a currency, txType is Withdrawal,
+// GiftSent, Lost or Sell. Sells are payments with the currency, they are
+// valued at the market value.
+func (r *Row) Outgoing(txType transaction.Type) (*transaction.Tx, error) {
+	if r.SentCurrency == transaction.CurrencyUndef {
+		return nil, fmt.Errorf("sent currency of %s is missing", txType)
+	}
+
+	tx := r.tx(txType, r.SentCurrency, r.Sent)
+
+	switch txType {
+	case transaction.Withdrawal, transaction.GiftSent, transaction.Lost:
+	case transaction.Sell:
+		r.valued(tx)
+	default:
+		return nil, fmt.Errorf("%s is not an outgoing transaction", txType)
+	}
+
+	return tx, nil
+}
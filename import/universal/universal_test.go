@@ -0,0 +1,127 @@
+package universal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/import/importtest"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+var ts = time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC)
+
+func dec(s string) math.Decimal {
+	return math.MustParseDecimal(s)
+}
+
+func TestRows(t *testing.T) {
+	rows := []struct {
+		name string
+		row  Row
+		conv func(*Row) (*transaction.Tx, error)
+	}{
+		{
+			name: "buy",
+			row:  Row{Sent: dec("300"), SentCurrency: transaction.EUR, Received: dec("0.01"), ReceivedCurrency: transaction.BTC, Fee: dec("1.5"), FeeCurrency: transaction.EUR},
+			conv: (*Row).Trade,
+		},
+		{
+			name: "sell",
+			row:  Row{Sent: dec("0.01"), SentCurrency: transaction.BTC, Received: dec("400"), ReceivedCurrency: transaction.EUR},
+			conv: (*Row).Trade,
+		},
+		{
+			name: "trade with € value",
+			row:  Row{Sent: dec("0.02"), SentCurrency: transaction.BTC, Received: dec("0.5"), ReceivedCurrency: transaction.ETH, Value: dec("820"), ValueCurrency: transaction.EUR},
+			conv: (*Row).Trade,
+		},
+		{
+			name: "trade with USD value",
+			row:  Row{Sent: dec("0.02"), SentCurrency: transaction.BTC, Received: dec("0.5"), ReceivedCurrency: transaction.ETH, Value: dec("1000"), ValueCurrency: transaction.USD},
+			conv: (*Row).Trade,
+		},
+		{
+			name: "income",
+			row:  Row{Received: dec("0.5"), ReceivedCurrency: transaction.ETH, Value: dec("400"), ValueCurrency: transaction.EUR},
+			conv: func(r *Row) (*transaction.Tx, error) { return r.Incoming(transaction.Income) },
+		},
+		{
+			name: "non-taxable income",
+			row:  Row{Received: dec("0.5"), ReceivedCurrency: transaction.ETH, Value: dec("400"), ValueCurrency: transaction.EUR},
+			conv: (*Row).NonTaxable,
+		},
+		{
+			name: "deposit",
+			row:  Row{Received: dec("0.5"), ReceivedCurrency: transaction.ETH, Value: dec("400"), ValueCurrency: transaction.EUR},
+			conv: func(r *Row) (*transaction.Tx, error) { return r.Incoming(transaction.Deposit) },
+		},
+		{
+			name: "payment",
+			row:  Row{Sent: dec("0.001"), SentCurrency: transaction.BTC, Value: dec("40")},
+			conv: func(r *Row) (*transaction.Tx, error) { return r.Outgoing(transaction.Sell) },
+		},
+		{
+			name: "withdrawal",
+			row:  Row{Sent: dec("0.1"), SentCurrency: transaction.ETH, Fee: dec("0.001"), FeeCurrency: transaction.ETH},
+			conv: func(r *Row) (*transaction.Tx, error) { return r.Outgoing(transaction.Withdrawal) },
+		},
+	}
+
+	var res []*transaction.Tx
+	for _, r := range rows {
+		r.row.Timestamp = ts
+
+		tx, err := r.conv(&r.row)
+		if err != nil {
+			t.Fatalf("%s: %s", r.name, err)
+		}
+		res = append(res, tx)
+	}
+
+	importtest.Check(t, res, []importtest.Tx{
+		{Timestamp: ts, Type: transaction.Buy, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "30000", Fees: "1.5", FeeCurrency: transaction.EUR},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.01", PayCurrency: transaction.EUR, SpotPrice: "40000"},
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.02", PayCurrency: transaction.ETH, SpotPrice: "25", Value: "820"},
+		// only € values are used as value of the trade
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.02", PayCurrency: transaction.ETH, SpotPrice: "25"},
+		{Type: transaction.Income, Currency: transaction.ETH, Quantity: "0.5", PayCurrency: transaction.EUR, SpotPrice: "800"},
+		{Type: transaction.Income, Currency: transaction.ETH, Quantity: "0.5", PayCurrency: transaction.EUR},
+		{Type: transaction.Deposit, Currency: transaction.ETH, Quantity: "0.5"},
+		// a value without currency is in €
+		{Type: transaction.Sell, Currency: transaction.BTC, Quantity: "0.001", PayCurrency: transaction.EUR, SpotPrice: "40000"},
+		{Type: transaction.Withdrawal, Currency: transaction.ETH, Quantity: "0.1", Fees: "0.001", FeeCurrency: transaction.ETH},
+	})
+}
+
+func TestInvalidRows(t *testing.T) {
+	if _, err := (&Row{Sent: dec("0"), SentCurrency: transaction.EUR, Received: dec("1"), ReceivedCurrency: transaction.BTC}).Trade(); err == nil {
+		t.Error("trade with a zero amount succeeded")
+	}
+
+	if _, err := (&Row{Received: dec("1"), ReceivedCurrency: transaction.BTC}).Trade(); err == nil {
+		t.Error("trade without sent currency succeeded")
+	}
+
+	if _, err := (&Row{Received: dec("1"), ReceivedCurrency: transaction.BTC}).Incoming(transaction.Sell); err == nil {
+		t.Error("incoming sell succeeded")
+	}
+
+	if _, err := (&Row{Sent: dec("1"), SentCurrency: transaction.BTC}).Incoming(transaction.Income); err == nil {
+		t.Error("income without received currency succeeded")
+	}
+
+	if _, err := (&Row{Sent: dec("1"), SentCurrency: transaction.BTC}).Outgoing(transaction.Buy); err == nil {
+		t.Error("outgoing buy succeeded")
+	}
+}
+
+func TestIsFiatTransfer(t *testing.T) {
+	if !(&Row{Received: dec("1000"), ReceivedCurrency: transaction.EUR}).IsFiatTransfer() {
+		t.Error("fiat deposit is no fiat transfer")
+	}
+
+	if (&Row{Sent: dec("300"), SentCurrency: transaction.EUR, Received: dec("0.01"), ReceivedCurrency: transaction.BTC}).IsFiatTransfer() {
+		t.Error("buy is a fiat transfer")
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/accounting"
+	"github.com/fho/cryptotax/config"
+	"github.com/fho/cryptotax/import/etherscan"
+	"github.com/fho/cryptotax/import/manual"
+	"github.com/fho/cryptotax/import/openingbalance"
+	"github.com/fho/cryptotax/ledger"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/price"
+	"github.com/fho/cryptotax/transaction"
+)
+
+var errNoTransactions = errors.New("no transactions found, import files with 'cryptotax import' or pass them with the file flags or -manual")
+
+// dataDir returns the ledger directory of the config or the default one.
+func dataDir(cfg *config.Config) string {
+	if len(cfg.DataDir) != 0 {
+		return cfg.DataDir
+	}
+
+	dir, err := ledger.DefaultDir()
+	if err != nil {
+		return ""
+	}
+
+	return dir
+}
+
+func stringOr(v, def string) string {
+	if len(v) == 0 {
+		return def
+	}
+
+	return v
+}
+
+// formatCurrencyValues returns values in the format that
+// parseCurrencyValues parses.
+func formatCurrencyValues(values map[string]math.Decimal) string {
+	var res []string
+
+	for cur, v := range values {
+		res = append(res, cur+"="+v.String())
+	}
+	sort.Strings(res)
+
+	return strings.Join(res, ",")
+}
+
+// parseCurrencyValues parses a list of values per currency in the format:
+// BTC=30000,ETH=2000
+func parseCurrencyValues(v string) (map[transaction.Currency]math.Decimal, error) {
+	res := map[transaction.Currency]math.Decimal{}
+
+	for _, kv := range strings.Split(v, ",") {
+		fields := strings.SplitN(kv, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("parsing %q failed: missing '='", kv)
+		}
+
+		cur, err := transaction.NewCurrency(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %s", fields[0], err)
+		}
+
+		value, err := math.ParseDecimal(fields[1])
+		if err != nil {
+			return nil, err
+		}
+
+		res[cur] = value
+	}
+
+	return res, nil
+}
+
+// inputFlags are the flags of the commands that calculate the lots of the
+// transactions in the ledger and the passed files.
+type inputFlags struct {
+	dataDir         string
+	files           exchangeFiles
+	manual          string
+	dust            string
+	dustPolicy      string
+	unmatched       string
+	openingPrices   string
+	openingDate     string
+	openingBalances string
+	prices          priceFlag
+}
+
+func (f *inputFlags) register(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&f.dataDir, "data-dir", dataDir(cfg), "directory of the transaction ledger")
+	f.files.register(fs, ", the files are used additionally to the ledger")
+	fs.StringVar(&f.manual, "manual", cfg.Manual, "path to a json file with manual transactions and corrections of imported transactions")
+	fs.StringVar(&f.dust, "dust", formatCurrencyValues(cfg.Report.Dust), "per currency lot balances up to that are treated as rounding remainders, format: BTC=0.00000001,ETH=0.000000001")
+	fs.StringVar(&f.dustPolicy, "dust-policy", stringOr(cfg.Report.DustPolicy, accounting.DustWriteOff.String()), "how rounding remainders are handled: write-off or carry (to the next lot)")
+	fs.StringVar(&f.unmatched, "unmatched-sells", stringOr(cfg.Report.UnmatchedSells, accounting.UnmatchedZeroBasis.String()), "how sells without buy records are handled: zero-basis, fail or opening-balance")
+	fs.StringVar(&f.openingPrices, "opening-prices", "", "per currency cost basis in € for sells without buy records, used by -unmatched-sells=opening-balance, format: BTC=1000,ETH=10")
+	fs.StringVar(&f.openingDate, "opening-date", "", "acquisition date for sells without buy records, used by -unmatched-sells=opening-balance")
+	fs.StringVar(&f.openingBalances, "opening-balances", cfg.OpeningBalances, "path to a csv file with holdings that were acquired before the imported transactions")
+	f.prices.register(fs, cfg)
+}
+
+// input is the calculation result for the transactions of the ledger and
+// the passed files.
+type input struct {
+	res         *accounting.Result
+	duplicates  []*transaction.Tx
+	annotations []*ledger.Annotation
+}
+
+func (f *inputFlags) records() ([]*transaction.Tx, []*ledger.Annotation, error) {
+	var records []*transaction.Tx
+
+	log.Infof("reading ledger in %s", f.dataDir)
+	l, err := ledger.Open(f.dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	records = append(records, l.Transactions()...)
+	etherscan.RegisterTokens(records)
+
+	err = f.files.read(func(_ *fileSource, _ *taggedFile, fileRecords []*transaction.Tx) error {
+		records = append(records, fileRecords...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return records, l.Annotations, nil
+}
+
+func (f *inputFlags) options() (accounting.Options, error) {
+	var opts accounting.Options
+	var err error
+
+	opts.DustPolicy, err = accounting.NewDustPolicy(f.dustPolicy)
+	if err != nil {
+		return opts, err
+	}
+
+	if len(f.dust) != 0 {
+		opts.DustThresholds, err = parseCurrencyValues(f.dust)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	if len(f.openingBalances) != 0 {
+		log.Infof("reading %s", f.openingBalances)
+		ob := openingbalance.Import{}
+		opts.OpeningBalances, err = ob.FromCSV(f.openingBalances)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	src, err := f.prices.source()
+	if err != nil {
+		return opts, err
+	}
+
+	if src != nil {
+		opts.Prices = src
+	}
+
+	opts.UnmatchedPolicy, err = accounting.NewUnmatchedPolicy(f.unmatched)
+	if err != nil {
+		return opts, err
+	}
+
+	if opts.UnmatchedPolicy == accounting.UnmatchedOpeningBalance {
+		opts.OpeningPrices, err = parseCurrencyValues(f.openingPrices)
+		if err != nil {
+			return opts, err
+		}
+
+		opts.OpeningDate, err = time.ParseInLocation("2006-01-02", f.openingDate, time.Local)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+// calculate reads the transactions and calculates the lots.
+func (f *inputFlags) calculate() (*input, error) {
+	records, annotations, err := f.records()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.manual) != 0 {
+		log.Infof("reading %s", f.manual)
+		mp := manual.Import{}
+		manualFile, err := mp.FromJSON(f.manual)
+		if err != nil {
+			return nil, err
+		}
+
+		records, err = manualFile.Apply(records)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// manual transactions are deduplicated too, they are appended after
+	// the imported ones, an imported transaction is kept
+	records, duplicates := transaction.Deduplicate(records)
+	for _, dup := range duplicates {
+		log.Infof("dropping duplicate transaction: %s (%s)", dup.ID, dup)
+	}
+
+	opts, err := f.options()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 && len(opts.OpeningBalances) == 0 {
+		return nil, errNoTransactions
+	}
+
+	book, err := accounting.NewBook(records)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := book.Calculate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &input{
+		res:         res,
+		duplicates:  duplicates,
+		annotations: annotations,
+	}, nil
+}
+
+// logFlags are the flags for the verbosity of the log messages.
+type logFlags struct {
+	quiet   bool
+	verbose bool
+	debug   bool
+}
+
+func (f *logFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.quiet, "q", false, "only log errors")
+	fs.BoolVar(&f.verbose, "v", false, "additionally log progress messages")
+	fs.BoolVar(&f.debug, "debug", false, "additionally log every accounting step")
+}
+
+func (f *logFlags) apply() {
+	switch {
+	case f.debug:
+		log.SetLevel(log.LevelDebug)
+	case f.verbose:
+		log.SetLevel(log.LevelInfo)
+	case f.quiet:
+		log.SetLevel(log.LevelError)
+	}
+}
+
+// outputFlag is the flag for the destination of a report.
+type outputFlag struct {
+	path string
+}
+
+func (f *outputFlag) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.path, "o", "", "write the output to this file instead of stdout")
+}
+
+func (f *outputFlag) write(s string) error {
+	if len(f.path) == 0 || f.path == "-" {
+		_, err := os.Stdout.WriteString(s)
+		return err
+	}
+
+	return os.WriteFile(f.path, []byte(s), 0644)
+}
+
+// listFlag is a flag that can be passed multiple times. Its default values
+// are replaced when it is passed.
+type listFlag struct {
+	values []string
+	set    bool
+}
+
+func (f *listFlag) String() string {
+	return strings.Join(f.values, ",")
+}
+
+func (f *listFlag) Set(v string) error {
+	if !f.set {
+		f.values = nil
+		f.set = true
+	}
+
+	f.values = append(f.values, v)
+
+	return nil
+}
+
+// priceFlag is the flag for the files with historical prices.
+type priceFlag struct {
+	sources listFlag
+	src     *price.Source // read on first use
+}
+
+func (f *priceFlag) register(fs *flag.FlagSet, cfg *config.Config) {
+	f.sources.values = cfg.PriceSources
+	fs.Var(&f.sources, "price-source", "path or glob of csv files with prices in €, used for fiat currencies other than € and as current prices, can be passed multiple times, format: date,currency,price")
+}
+
+// source reads the price files, nil is returned if none are passed.
+func (f *priceFlag) source() (*price.Source, error) {
+	var paths []string
+
+	if f.src != nil || len(f.sources.values) == 0 {
+		return f.src, nil
+	}
+
+	for _, pattern := range f.sources.values {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %s", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("price source %s does not exist", pattern)
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	log.Infof("reading prices from %s", strings.Join(paths, ", "))
+
+	src, err := price.ReadCSV(paths...)
+	if err != nil {
+		return nil, err
+	}
+	f.src = src
+
+	return src, nil
+}
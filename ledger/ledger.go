@@ -0,0 +1,294 @@
+// Package ledger stores imported transactions in a local data directory.
+// Files only have to be imported once, afterwards the transactions are read
+// from the ledger.
+//
+// The ledger is a single JSON file in the data directory. Besides the
+// transactions it contains the imported source files, identified by the
+// SHA256 hash of their content, and notes of the user about transactions.
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fho/cryptotax/transaction"
+)
+
+// FileName is the name of the ledger file in the data directory.
+const FileName = "ledger.json"
+
+// Source is an imported file.
+type Source struct {
+	Path       string    `json:"path"`
+	SHA256     string    `json:"sha256"`
+	Exchange   string    `json:"exchange"`
+	Wallet     string    `json:"wallet,omitempty"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// Entry is an imported transaction.
+type Entry struct {
+	Tx         *transaction.Tx `json:"tx"`
+	Source     string          `json:"source"` // SHA256 of the source file
+	ImportedAt time.Time       `json:"imported_at"`
+}
+
+// Annotation is a note of the user about a transaction.
+type Annotation struct {
+	Exchange  string    `json:"exchange"`
+	ID        string    `json:"id"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Ledger contains all imported transactions.
+type Ledger struct {
+	dir string
+
+	// Currencies are the symbols of the registered currencies of the
+	// entries, e.g. of tokens, they are registered when the ledger is
+	// opened
+	Currencies  []string      `json:"currencies,omitempty"`
+	Sources     []*Source     `json:"sources"`
+	Entries     []*Entry      `json:"entries"`
+	Annotations []*Annotation `json:"annotations"`
+}
+
+// ImportResult describes the changes of an import.
+type ImportResult struct {
+	Source *Source
+	// AlreadyImported is true if a file with the same content was
+	// imported before, nothing was added
+	AlreadyImported bool
+	Added           []*transaction.Tx
+	// Duplicates are the transactions that already exist in the ledger
+	Duplicates []*transaction.Tx
+}
+
+// DefaultDir returns the default data directory,
+// $XDG_DATA_HOME/cryptotax or ~/.local/share/cryptotax.
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); len(dir) != 0 {
+		return filepath.Join(dir, "cryptotax"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", "cryptotax"), nil
+}
+
+// Open reads the ledger in dir. If it does not exist an empty ledger is
+// returned, it is created by Save.
+func Open(dir string) (*Ledger, error) {
+	res := Ledger{dir: dir}
+
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &res, nil
+		}
+
+		return nil, err
+	}
+
+	// the currencies must be registered before the entries are parsed
+	var currencies struct {
+		Currencies []string `json:"currencies"`
+	}
+	err = json.Unmarshal(data, &currencies)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %s", filepath.Join(dir, FileName), err)
+	}
+
+	for _, symbol := range currencies.Currencies {
+		_, err := transaction.RegisterCurrency(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s failed: registering currency %q failed: %s", filepath.Join(dir, FileName), symbol, err)
+		}
+	}
+
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %s", filepath.Join(dir, FileName), err)
+	}
+
+	return &res, nil
+}
+
+// Save writes the ledger to its data directory.
+// The file is replaced atomically, an interrupted write does not corrupt
+// the ledger.
+func (l *Ledger) Save() error {
+	l.Currencies = l.registeredCurrencies()
+
+	err := os.MkdirAll(l.dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(l.dir, FileName+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(l.dir, FileName))
+}
+
+// Transactions returns the transactions of the ledger.
+func (l *Ledger) Transactions() []*transaction.Tx {
+	res := make([]*transaction.Tx, 0, len(l.Entries))
+	for _, e := range l.Entries {
+		res = append(res, e.Tx)
+	}
+
+	return res
+}
+
+// registeredCurrencies returns the sorted symbols of the currencies of the
+// entries that are not predefined.
+func (l *Ledger) registeredCurrencies() []string {
+	var res []string
+	seen := map[transaction.Currency]bool{}
+
+	for _, e := range l.Entries {
+		for _, c := range []transaction.Currency{e.Tx.Currency, e.Tx.PayCurrency, e.Tx.FeeCurrency} {
+			if c == transaction.CurrencyUndef || c.Predefined() || seen[c] {
+				continue
+			}
+
+			seen[c] = true
+			res = append(res, c.String())
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// FileHash returns the hex encoded SHA256 hash of the file content.
+func FileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Import adds records that were read from the file at path to the ledger,
+// wallet is the account or wallet tag of the file.
+// Transactions that already exist in the ledger are skipped. If a file
+// with the same content was imported before nothing is added. An error is
+// returned if it was imported with another wallet, the transactions
+// would be added a second time because they are distinguished by their
+// wallet.
+func (l *Ledger) Import(path, exchange, wallet string, records []*transaction.Tx) (*ImportResult, error) {
+	hash, err := FileHash(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, src := range l.Sources {
+		if src.SHA256 != hash {
+			continue
+		}
+
+		if src.Wallet != wallet {
+			return nil, fmt.Errorf("%s: the same content was already imported from %s with wallet %q, it can not be imported again with wallet %q",
+				path, src.Path, src.Wallet, wallet)
+		}
+
+		return &ImportResult{Source: src, AlreadyImported: true}, nil
+	}
+
+	src := Source{
+		Path:       path,
+		SHA256:     hash,
+		Exchange:   exchange,
+		Wallet:     wallet,
+		ImportedAt: time.Now(),
+	}
+
+	existing := l.Transactions()
+	unique, duplicates := transaction.Deduplicate(append(existing, records...))
+	added := unique[len(existing):]
+
+	for _, tx := range added {
+		l.Entries = append(l.Entries, &Entry{
+			Tx:         tx,
+			Source:     hash,
+			ImportedAt: src.ImportedAt,
+		})
+	}
+
+	l.Sources = append(l.Sources, &src)
+
+	return &ImportResult{
+		Source:     &src,
+		Added:      added,
+		Duplicates: duplicates,
+	}, nil
+}
+
+// Annotate adds a note to the transaction with the given exchange and id.
+func (l *Ledger) Annotate(exchange, id, note string) error {
+	var found bool
+
+	for _, e := range l.Entries {
+		if e.Tx.ID == id && (len(exchange) == 0 || e.Tx.Exchange == exchange) {
+			exchange = e.Tx.Exchange
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("transaction %s does not exist in the ledger", id)
+	}
+
+	l.Annotations = append(l.Annotations, &Annotation{
+		Exchange:  exchange,
+		ID:        id,
+		Note:      note,
+		CreatedAt: time.Now(),
+	})
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package ledger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestImportWithAnotherWallet(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "trades.csv")
+	err := os.WriteFile(path, []byte("id,amount\n1,0.5\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := func(wallet string) []*transaction.Tx {
+		return []*transaction.Tx{{
+			ID:          "1",
+			Exchange:    "Test",
+			Wallet:      wallet,
+			Timestamp:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			Type:        transaction.Buy,
+			Currency:    transaction.BTC,
+			PayCurrency: transaction.EUR,
+			Quantity:    math.MustParseDecimal("0.5"),
+			SpotPrice:   math.MustParseDecimal("30000"),
+		}}
+	}
+
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = l.Import(path, "Test", "", records(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := l.Import(path, "Test", "", records(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.AlreadyImported {
+		t.Error("importing the file again with the same wallet was not skipped")
+	}
+
+	_, err = l.Import(path, "Test", "savings", records("savings"))
+	if err == nil {
+		t.Error("importing the file again with another wallet succeeded")
+	}
+
+	if len(l.Entries) != 1 {
+		t.Errorf("ledger has %d entries, want 1", len(l.Entries))
+	}
+}
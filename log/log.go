@@ -0,0 +1,72 @@
+// Package log writes leveled log messages to stderr.
+// Messages of a lower priority then the configured level are discarded.
+package log
+
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+)
+
+// Level is the priority of log messages.
+type Level int
+
+const (
+	// LevelError only logs errors
+	LevelError Level = iota
+	// LevelWarn logs errors and warnings, it is the default level
+	LevelWarn
+	// LevelInfo additionally logs progress messages
+	LevelInfo
+	// LevelDebug additionally logs every accounting step
+	LevelDebug
+)
+
+var level = LevelWarn
+
+var logger = stdlog.New(os.Stderr, "", stdlog.LstdFlags)
+
+// SetLevel sets the level up to that messages are logged.
+func SetLevel(l Level) {
+	level = l
+}
+
+// SetOutput sets the destination of log messages.
+func SetOutput(w io.Writer) {
+	logger.SetOutput(w)
+}
+
+func logf(l Level, prefix, format string, v ...interface{}) {
+	if l > level {
+		return
+	}
+
+	logger.Output(3, prefix+fmt.Sprintf(format, v...))
+}
+
+// Errorf logs an error message.
+func Errorf(format string, v ...interface{}) {
+	logf(LevelError, "ERROR: ", format, v...)
+}
+
+// Warnf logs a warning.
+func Warnf(format string, v ...interface{}) {
+	logf(LevelWarn, "WARN: ", format, v...)
+}
+
+// Infof logs a progress message.
+func Infof(format string, v ...interface{}) {
+	logf(LevelInfo, "", format, v...)
+}
+
+// Debugf logs a debug message.
+func Debugf(format string, v ...interface{}) {
+	logf(LevelDebug, "", format, v...)
+}
+
+// Fatalf logs an error message and terminates the program with exit code 1.
+func Fatalf(format string, v ...interface{}) {
+	logf(LevelError, "ERROR: ", format, v...)
+	os.Exit(1)
+}
@@ -1,80 +1,183 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
-	"time"
+	"strings"
+	"text/tabwriter"
 
-	"github.com/fho/cryptotax/accounting"
-	"github.com/fho/cryptotax/import/coinbase"
-	"github.com/fho/cryptotax/import/kraken"
-	"github.com/fho/cryptotax/transaction"
+	"github.com/fho/cryptotax/config"
+	"github.com/fho/cryptotax/log"
 )
 
-func errCheck(err error) {
-	if err == nil {
-		return
+// exit codes
+const (
+	exitOK               = 0
+	exitError            = 1
+	exitUsage            = 2
+	exitValidationFailed = 3
+)
+
+var (
+	errUsage            = errors.New("invalid usage")
+	errValidationFailed = errors.New("validation found problems")
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, args []string) error
+}
+
+var commands = []*command{
+	{"import", "import exchange files into the ledger", importFiles},
+	{"annotate", "add a note to a transaction in the ledger", annotate},
+	{"report", "print the tax report of a year", report},
+	{"holdings", "print when the current holdings become tax free", holdings},
+	{"lots", "print all lots and the sells that were matched to them", lots},
+	{"simulate", "print the tax consequences of a hypothetical sell", simulate},
+	{"validate", "check the transactions for problems", validate},
+	{"export", "write the tax records of a year as csv", export},
+}
+
+func usage() {
+	var buf bytes.Buffer
+
+	buf.WriteString("Usage: cryptotax <command> [flags]\n\nCommands:\n")
+
+	tw := tabwriter.NewWriter(&buf, 0, 4, 4, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
 	}
+	tw.Flush()
 
-	log.Fatalln(err)
+	buf.WriteString("\nRun 'cryptotax <command> -h' to show the flags of a command.\n")
+	buf.WriteString("\nExit codes:\n")
+	fmt.Fprintf(&buf, "  %d  success\n", exitOK)
+	fmt.Fprintf(&buf, "  %d  error\n", exitError)
+	fmt.Fprintf(&buf, "  %d  invalid command or flags\n", exitUsage)
+	fmt.Fprintf(&buf, "  %d  validate found problems\n", exitValidationFailed)
+
+	os.Stderr.Write(buf.Bytes())
 }
 
-/*
-	TODO:
-	- if a currency is bought in a cryptocurrency, the owned amount of it has to be reduced
-	- add testcases
-	- review big float use
-*/
+func newFlagSet(name, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	// the value is read by configPath before the flags are parsed
+	fs.String("config", defaultConfigPath(), "path of the config file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cryptotax %s [flags]\n\n%s.\n\nFlags:\n", name, description)
+		fs.PrintDefaults()
+	}
 
-func main() {
-	var coinbaseFileFlag string
-	var krakenFileFlag string
-	var taxYear uint
-
-	flag.StringVar(&coinbaseFileFlag, "coinbase-csv", "", "path to a coinbase taxhistory csv file")
-	flag.StringVar(&krakenFileFlag, "kraken-csv", "", "path to a kraken trades csv file")
-	flag.UintVar(&taxYear, "tax-year", uint(time.Now().Year())-1, "year for that the report is created")
-	flag.Parse()
-
-	if len(coinbaseFileFlag) == 0 && len(krakenFileFlag) == 0 {
-		fmt.Printf("Error: You have to specify the path to at least 1 csv file\n\n")
-		flag.Usage()
-		os.Exit(1)
+	return fs
+}
+
+// parseFlags parses args, on failure errUsage or flag.ErrHelp is returned.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+
+		return errUsage
+	}
+
+	if fs.NArg() != 0 {
+		return usagef(fs, "unexpected arguments: %v", fs.Args())
+	}
+
+	return nil
+}
+
+// usagef prints the error message and the usage of the command and returns
+// errUsage.
+func usagef(fs *flag.FlagSet, format string, v ...interface{}) error {
+	fmt.Fprintf(fs.Output(), "Error: "+format+"\n\n", v...)
+	fs.Usage()
+
+	return errUsage
+}
+
+func defaultConfigPath() string {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return ""
+	}
+
+	return path
+}
+
+// configPath returns the value of the -config flag in args or the default
+// path. explicit is true if the flag was passed.
+func configPath(args []string) (path string, explicit bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if name == "config" && i+1 < len(args) {
+			return args[i+1], true
+		}
+
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config="), true
+		}
+	}
+
+	return defaultConfigPath(), false
+}
+
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, errValidationFailed):
+		return exitValidationFailed
 	}
 
-	var records []*transaction.Tx
+	log.Errorf("%s", err)
 
-	if len(coinbaseFileFlag) != 0 {
-		log.Printf("reading %s", coinbaseFileFlag)
-		cp := coinbase.Import{}
-		coinbaseRecords, err := cp.FromCSV(coinbaseFileFlag)
-		errCheck(err)
-		records = append(records, coinbaseRecords...)
+	return exitError
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
 	}
 
-	if len(krakenFileFlag) != 0 {
-		log.Printf("reading %s", krakenFileFlag)
-		cp := kraken.Import{}
-		krakenRecords, err := cp.FromCSV(krakenFileFlag)
-		errCheck(err)
-		records = append(records, krakenRecords...)
+	name := os.Args[1]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage()
+		os.Exit(exitOK)
 	}
 
-	book, err := accounting.NewBook(records, int(taxYear))
-	errCheck(err)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
 
-	err = book.Calculate()
-	errCheck(err)
+		args := os.Args[2:]
 
-	fmt.Println(book)
-	fmt.Println()
-	fmt.Println("TAX REPORT Full")
-	fmt.Println(book.TaxReport(true))
-	fmt.Println("================")
-	fmt.Printf("TAX REPORT %v\n", taxYear)
-	fmt.Println(book.TaxReport(false))
+		path, explicit := configPath(args)
+		cfg, err := config.Load(path, explicit)
+		if err != nil {
+			os.Exit(exitCode(err))
+		}
+
+		os.Exit(exitCode(cmd.run(cfg, args)))
+	}
 
-	fmt.Println()
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+	usage()
+	os.Exit(exitUsage)
 }
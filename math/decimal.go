@@ -0,0 +1,402 @@
+package math
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode defines how digits are dropped when a Decimal is rounded.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest neighbour, ties to the even
+	// neighbour (banker's rounding)
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest neighbour, ties away from zero
+	RoundHalfUp
+	// RoundDown rounds towards zero (truncation)
+	RoundDown
+	// RoundUp rounds away from zero
+	RoundUp
+)
+
+// DivScale is the number of fractional digits that is used for the
+// results of divisions when no other scale is required.
+const DivScale = 18
+
+// MaxScale is the maximal absolute exponent and scale of parsed numbers,
+// larger values would require huge allocations, e.g. for 1e2000000000.
+const MaxScale = 1000
+
+var ErrInvalidDecimal = errors.New("invalid decimal number")
+
+// Decimal is an exact decimal number with a fixed number of fractional
+// digits (scale). Its value is unscaled * 10^-scale.
+// Decimal values are immutable, all operations return a new value. The
+// zero value is 0.
+// Add, Sub and Mul are exact, Quo and Round require an explicit scale and
+// rounding mode.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+var bigTen = big.NewInt(10)
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// NewDecimal returns the Decimal unscaled * 10^-scale.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{unscaled: new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))}
+	}
+
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal parses a decimal number in the format
+// [+-]digits[.digits][(e|E)[+-]digits]. The scale of the result is the
+// number of fractional digits in s. Numbers with an exponent or scale
+// larger than MaxScale are rejected.
+func ParseDecimal(s string) (Decimal, error) {
+	var exp int64
+	var err error
+
+	str := strings.TrimSpace(s)
+
+	if idx := strings.IndexAny(str, "eE"); idx != -1 {
+		exp, err = strconv.ParseInt(str[idx+1:], 10, 32)
+		if err != nil || exp > MaxScale || exp < -MaxScale {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+		}
+		str = str[:idx]
+	}
+
+	var neg bool
+	if len(str) > 0 && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+
+	intPart, fracPart := str, ""
+	if idx := strings.IndexByte(str, '.'); idx != -1 {
+		intPart, fracPart = str[:idx], str[idx+1:]
+	}
+
+	digits := intPart + fracPart
+	if len(digits) == 0 {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+		}
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		unscaled.Neg(unscaled)
+	}
+
+	scale := int64(len(fracPart)) - exp
+	if scale > MaxScale || scale < -MaxScale {
+		return Decimal{}, fmt.Errorf("%w: scale is out of range: %q", ErrInvalidDecimal, s)
+	}
+
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(int32(-scale)))
+		scale = 0
+	}
+
+	return Decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics if s is invalid.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+
+	return d.unscaled
+}
+
+// align returns the unscaled values of x and y with the same scale.
+func align(x, y Decimal) (*big.Int, *big.Int, int32) {
+	xi, yi := x.int(), y.int()
+
+	switch {
+	case x.scale < y.scale:
+		xi = new(big.Int).Mul(xi, pow10(y.scale-x.scale))
+		return xi, yi, y.scale
+	case x.scale > y.scale:
+		yi = new(big.Int).Mul(yi, pow10(x.scale-y.scale))
+		return xi, yi, x.scale
+	}
+
+	return xi, yi, x.scale
+}
+
+// Scale returns the number of fractional digits of d.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Add returns d + y.
+func (d Decimal) Add(y Decimal) Decimal {
+	xi, yi, scale := align(d, y)
+	return Decimal{unscaled: new(big.Int).Add(xi, yi), scale: scale}
+}
+
+// Sub returns d - y.
+func (d Decimal) Sub(y Decimal) Decimal {
+	xi, yi, scale := align(d, y)
+	return Decimal{unscaled: new(big.Int).Sub(xi, yi), scale: scale}
+}
+
+// Mul returns d * y.
+func (d Decimal) Mul(y Decimal) Decimal {
+	return Decimal{
+		unscaled: new(big.Int).Mul(d.int(), y.int()),
+		scale:    d.scale + y.scale,
+	}
+}
+
+// Quo returns d / y rounded to scale fractional digits.
+// It panics if y is zero.
+func (d Decimal) Quo(y Decimal, scale int32, mode RoundingMode) Decimal {
+	if y.IsZero() {
+		panic("math: division by zero")
+	}
+
+	num := d.int()
+	den := y.int()
+
+	// d/y * 10^scale = d.unscaled * 10^(scale-d.scale+y.scale) / y.unscaled
+	e := scale - d.scale + y.scale
+	if e >= 0 {
+		num = new(big.Int).Mul(num, pow10(e))
+	} else {
+		den = new(big.Int).Mul(den, pow10(-e))
+	}
+
+	return Decimal{unscaled: roundQuo(num, den, mode), scale: scale}
+}
+
+// roundQuo returns num/den rounded to an integer.
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+
+	// 1 if the exact result is positive, -1 if it is negative
+	sign := int64(num.Sign() * den.Sign())
+
+	// cmpHalf is the result of comparing |rem| with |den|/2
+	twiceRem := new(big.Int).Abs(rem)
+	twiceRem.Lsh(twiceRem, 1)
+	cmpHalf := twiceRem.Cmp(new(big.Int).Abs(den))
+
+	var awayFromZero bool
+	switch mode {
+	case RoundDown:
+		awayFromZero = false
+	case RoundUp:
+		awayFromZero = true
+	case RoundHalfUp:
+		awayFromZero = cmpHalf >= 0
+	default:
+		awayFromZero = cmpHalf > 0 || (cmpHalf == 0 && q.Bit(0) == 1)
+	}
+
+	if awayFromZero {
+		q.Add(q, big.NewInt(sign))
+	}
+
+	return q
+}
+
+// Round returns d with scale fractional digits. If digits have to be
+// dropped they are rounded according to mode.
+func (d Decimal) Round(scale int32, mode RoundingMode) Decimal {
+	if scale >= d.scale {
+		return Decimal{
+			unscaled: new(big.Int).Mul(d.int(), pow10(scale-d.scale)),
+			scale:    scale,
+		}
+	}
+
+	return Decimal{
+		unscaled: roundQuo(d.int(), pow10(d.scale-scale), mode),
+		scale:    scale,
+	}
+}
+
+// Normalize returns d without trailing fractional zeros, e.g. 1.50 is
+// returned as 1.5. The value is unchanged.
+func (d Decimal) Normalize() Decimal {
+	unscaled := new(big.Int).Set(d.int())
+	scale := d.scale
+
+	if unscaled.Sign() == 0 {
+		return Decimal{unscaled: unscaled}
+	}
+
+	q, rem := new(big.Int), new(big.Int)
+	for scale > 0 {
+		q.QuoRem(unscaled, bigTen, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+
+		unscaled.Set(q)
+		scale--
+	}
+
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Abs returns |d|.
+func (d Decimal) Abs() Decimal {
+	return Decimal{unscaled: new(big.Int).Abs(d.int()), scale: d.scale}
+}
+
+// Sign returns -1 if d < 0, 0 if d == 0 and +1 if d > 0.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// IsZero returns true if d is 0.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and y, it returns -1 if d < y, 0 if d == y and +1 if
+// d > y.
+func (d Decimal) Cmp(y Decimal) int {
+	xi, yi, _ := align(d, y)
+	return xi.Cmp(yi)
+}
+
+// Min returns the smaller value of d and y.
+func (d Decimal) Min(y Decimal) Decimal {
+	if d.Cmp(y) <= 0 {
+		return d
+	}
+
+	return y
+}
+
+// Float64 returns the float64 value nearest to d.
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.int(), pow10(d.scale)).Float64()
+	return f
+}
+
+// String returns the exact value of d with Scale() fractional digits.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+
+	var sign string
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.scale == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= int(d.scale) {
+		digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+	}
+
+	idx := len(digits) - int(d.scale)
+
+	return sign + digits[:idx] + "." + digits[idx:]
+}
+
+// StringFixed returns d rounded half up to scale fractional digits.
+func (d Decimal) StringFixed(scale int32) string {
+	return d.Round(scale, RoundHalfUp).String()
+}
+
+// Format implements fmt.Formatter. The verbs %s, %v and %f without
+// precision print the exact value, a precision for %f rounds the value half
+// up.
+func (d Decimal) Format(f fmt.State, verb rune) {
+	var str string
+
+	switch verb {
+	case 'f', 's', 'v':
+		str = d.String()
+		if prec, ok := f.Precision(); ok && verb == 'f' {
+			str = d.StringFixed(int32(prec))
+		}
+	default:
+		fmt.Fprintf(f, "%%!%c(math.Decimal=%s)", verb, d.String())
+		return
+	}
+
+	if width, ok := f.Width(); ok && len(str) < width {
+		pad := strings.Repeat(" ", width-len(str))
+		if f.Flag('-') {
+			str += pad
+		} else {
+			str = pad + str
+		}
+	}
+
+	f.Write([]byte(str))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	res, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = res
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it accepts JSON numbers and
+// strings.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if str == "null" {
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(str); err == nil {
+		str = unquoted
+	}
+
+	return d.UnmarshalText([]byte(str))
+}
@@ -0,0 +1,291 @@
+package math
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		scale int32
+	}{
+		{in: "0", want: "0", scale: 0},
+		{in: "-0", want: "0", scale: 0},
+		{in: "0.1", want: "0.1", scale: 1},
+		{in: "-0.10", want: "-0.10", scale: 2},
+		{in: "+5", want: "5", scale: 0},
+		{in: " 42 ", want: "42", scale: 0},
+		{in: ".5", want: "0.5", scale: 1},
+		{in: "5.", want: "5", scale: 0},
+		{in: "0.00000001", want: "0.00000001", scale: 8},
+		{in: "1e3", want: "1000", scale: 0},
+		{in: "1.5E2", want: "150", scale: 0},
+		{in: "1.5e-3", want: "0.0015", scale: 4},
+		{in: "-2.50e1", want: "-25.0", scale: 1},
+		{in: "1e1000", want: "1" + zeros(1000), scale: 0},
+		{in: "12345678901234567890.123456789012345678", want: "12345678901234567890.123456789012345678", scale: 18},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		if err != nil {
+			t.Errorf("ParseDecimal(%q) returned error: %s", tt.in, err)
+			continue
+		}
+
+		if got := d.String(); got != tt.want {
+			t.Errorf("ParseDecimal(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+
+		if d.Scale() != tt.scale {
+			t.Errorf("ParseDecimal(%q) has scale %d, want %d", tt.in, d.Scale(), tt.scale)
+		}
+
+		// the string representation must be parsed to the same value
+		// and scale
+		rt, err := ParseDecimal(d.String())
+		if err != nil {
+			t.Errorf("parsing string %q of %q returned error: %s", d.String(), tt.in, err)
+			continue
+		}
+
+		if rt.Cmp(d) != 0 || rt.Scale() != d.Scale() {
+			t.Errorf("round trip of %q returned %s with scale %d, want %s with scale %d",
+				tt.in, rt, rt.Scale(), d, d.Scale())
+		}
+	}
+}
+
+func zeros(n int) string {
+	res := make([]byte, n)
+	for i := range res {
+		res[i] = '0'
+	}
+
+	return string(res)
+}
+
+func TestParseDecimalInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"-",
+		".",
+		"abc",
+		"1,5",
+		"1.2.3",
+		"--1",
+		"1e",
+		"1e1.5",
+		"0x10",
+		"1e1001",
+		"1e-1001",
+		"1e2000000000",
+		"1e-2000000000",
+		"1e99999999999",
+		"0." + zeros(1001),
+	}
+
+	for _, in := range tests {
+		_, err := ParseDecimal(in)
+		if !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("ParseDecimal(%q) returned %v, want ErrInvalidDecimal", in, err)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		x, y          string
+		sum, diff, mp string
+	}{
+		{x: "0.1", y: "0.2", sum: "0.3", diff: "-0.1", mp: "0.02"},
+		{x: "1.50", y: "0.5", sum: "2.00", diff: "1.00", mp: "0.750"},
+		{x: "-3", y: "0.003", sum: "-2.997", diff: "-3.003", mp: "-0.009"},
+		{x: "0", y: "12.34", sum: "12.34", diff: "-12.34", mp: "0.00"},
+	}
+
+	for _, tt := range tests {
+		x, y := MustParseDecimal(tt.x), MustParseDecimal(tt.y)
+
+		if got := x.Add(y).String(); got != tt.sum {
+			t.Errorf("%s + %s = %s, want %s", tt.x, tt.y, got, tt.sum)
+		}
+
+		if got := x.Sub(y).String(); got != tt.diff {
+			t.Errorf("%s - %s = %s, want %s", tt.x, tt.y, got, tt.diff)
+		}
+
+		if got := x.Mul(y).String(); got != tt.mp {
+			t.Errorf("%s * %s = %s, want %s", tt.x, tt.y, got, tt.mp)
+		}
+	}
+
+	var zero Decimal
+	if got := zero.Add(MustParseDecimal("1.5")).String(); got != "1.5" {
+		t.Errorf("zero value + 1.5 = %s, want 1.5", got)
+	}
+}
+
+func TestQuo(t *testing.T) {
+	tests := []struct {
+		x, y  string
+		scale int32
+		mode  RoundingMode
+		want  string
+	}{
+		{x: "1", y: "3", scale: 4, mode: RoundHalfEven, want: "0.3333"},
+		{x: "2", y: "3", scale: 4, mode: RoundHalfEven, want: "0.6667"},
+		{x: "2", y: "3", scale: 4, mode: RoundDown, want: "0.6666"},
+		{x: "-2", y: "3", scale: 4, mode: RoundDown, want: "-0.6666"},
+		{x: "-2", y: "3", scale: 4, mode: RoundUp, want: "-0.6667"},
+		{x: "1", y: "-3", scale: 4, mode: RoundUp, want: "-0.3334"},
+		{x: "1", y: "8", scale: 2, mode: RoundHalfEven, want: "0.12"},
+		{x: "1", y: "8", scale: 2, mode: RoundHalfUp, want: "0.13"},
+		{x: "3", y: "8", scale: 2, mode: RoundHalfEven, want: "0.38"},
+		{x: "10", y: "4", scale: 0, mode: RoundHalfEven, want: "2"},
+		{x: "10", y: "4", scale: 0, mode: RoundHalfUp, want: "3"},
+		{x: "-10", y: "4", scale: 0, mode: RoundHalfUp, want: "-3"},
+		{x: "1.5", y: "0.5", scale: 0, mode: RoundHalfEven, want: "3"},
+		{x: "100", y: "0.03", scale: 2, mode: RoundHalfEven, want: "3333.33"},
+		{x: "0.000001", y: "1000", scale: 8, mode: RoundHalfEven, want: "0.00000000"},
+		{x: "300", y: "0.01", scale: DivScale, mode: RoundHalfEven, want: "30000.000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		got := MustParseDecimal(tt.x).Quo(MustParseDecimal(tt.y), tt.scale, tt.mode).String()
+		if got != tt.want {
+			t.Errorf("%s / %s (scale %d, mode %d) = %s, want %s", tt.x, tt.y, tt.scale, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestQuoByZeroPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("division by zero did not panic")
+		}
+	}()
+
+	MustParseDecimal("1").Quo(Decimal{}, 2, RoundHalfEven)
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in    string
+		scale int32
+		mode  RoundingMode
+		want  string
+	}{
+		{in: "2.345", scale: 2, mode: RoundHalfEven, want: "2.34"},
+		{in: "2.355", scale: 2, mode: RoundHalfEven, want: "2.36"},
+		{in: "2.345", scale: 2, mode: RoundHalfUp, want: "2.35"},
+		{in: "2.345", scale: 2, mode: RoundDown, want: "2.34"},
+		{in: "2.341", scale: 2, mode: RoundUp, want: "2.35"},
+		{in: "-2.345", scale: 2, mode: RoundHalfUp, want: "-2.35"},
+		{in: "-2.345", scale: 2, mode: RoundDown, want: "-2.34"},
+		{in: "-2.341", scale: 2, mode: RoundUp, want: "-2.35"},
+		{in: "1.5", scale: 3, mode: RoundHalfEven, want: "1.500"},
+		{in: "0.5", scale: 0, mode: RoundHalfEven, want: "0"},
+		{in: "1.5", scale: 0, mode: RoundHalfEven, want: "2"},
+	}
+
+	for _, tt := range tests {
+		got := MustParseDecimal(tt.in).Round(tt.scale, tt.mode).String()
+		if got != tt.want {
+			t.Errorf("Round(%s, %d, mode %d) = %s, want %s", tt.in, tt.scale, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"1.500":   "1.5",
+		"100":     "100",
+		"0.000":   "0",
+		"-2.10":   "-2.1",
+		"1e-3":    "0.001",
+		"10.0000": "10",
+	}
+
+	for in, want := range tests {
+		if got := MustParseDecimal(in).Normalize().String(); got != want {
+			t.Errorf("Normalize(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	d := MustParseDecimal("1.005")
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "%s", want: "1.005"},
+		{format: "%v", want: "1.005"},
+		{format: "%f", want: "1.005"},
+		{format: "%.2f", want: "1.01"},
+		{format: "%.0f", want: "1"},
+		{format: "%.5f", want: "1.00500"},
+		{format: "%8.2f", want: "    1.01"},
+		{format: "%-7s|", want: "1.005  |"},
+		{format: "%d", want: "%!d(math.Decimal=1.005)"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, d); got != tt.want {
+			t.Errorf("Sprintf(%q, %s) = %q, want %q", tt.format, d, got, tt.want)
+		}
+	}
+
+	if got := fmt.Sprintf("%.2f", MustParseDecimal("-1.005")); got != "-1.01" {
+		t.Errorf("Sprintf(%%.2f, -1.005) = %q, want -1.01", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type doc struct {
+		D Decimal  `json:"d"`
+		P *Decimal `json:"p"`
+	}
+
+	in := doc{D: MustParseDecimal("0.10")}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"d":"0.10","p":null}` {
+		t.Errorf("json.Marshal returned %s", data)
+	}
+
+	var out doc
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.D.String() != "0.10" || out.P != nil {
+		t.Errorf("json round trip returned %+v", out)
+	}
+
+	for _, in := range []string{`{"d":0.10}`, `{"d":"0.10"}`, `{"d":1.0e-1}`} {
+		var out doc
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %s", in, err)
+			continue
+		}
+
+		if out.D.Cmp(MustParseDecimal("0.1")) != 0 {
+			t.Errorf("json.Unmarshal(%s) returned %s, want 0.1", in, out.D)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"d":"1e2000000000"}`), &out); err == nil {
+		t.Error("json.Unmarshal of a huge exponent succeeded")
+	}
+}
@@ -1,9 +0,0 @@
-package math
-
-import "math/big"
-
-const FloatPrec = 100
-
-func NewFloat() *big.Float {
-	return new(big.Float).SetPrec(FloatPrec)
-}
@@ -0,0 +1,161 @@
+// Package price provides historical prices of currencies from CSV files in
+// the format:
+//
+//	date,currency,price
+//	2021-03-01,BTC,41000.5
+//	2021-03-01T12:00:00Z,ETH,1300
+//
+// date is either a RFC3339 timestamp or a day, price is the price per unit
+// in the base currency (EUR). Prices of currencies that do not occur in the
+// transactions are skipped. A price is used until MaxAge after its date.
+package price
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fho/cryptotax/import/csvfile"
+	"github.com/fho/cryptotax/log"
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+type point struct {
+	ts    time.Time
+	price math.Decimal
+}
+
+// MaxAge is the maximal age of a price, older prices are not used. It
+// covers weekends and holidays without reference rates of fiat currencies.
+const MaxAge = 7 * 24 * time.Hour
+
+// Source contains prices per currency.
+type Source struct {
+	prices map[transaction.Currency][]point // ordered by ts
+	// stale contains the currencies for that an outdated price was
+	// requested, the warning is only logged once
+	stale map[transaction.Currency]bool
+}
+
+// timeFormats of the dates, days are in the local time zone
+var timeFormats = []string{time.RFC3339, "2006-01-02"}
+
+// ReadCSV reads the prices of the files at paths.
+func ReadCSV(paths ...string) (*Source, error) {
+	res := Source{
+		prices: map[transaction.Currency][]point{},
+		stale:  map[transaction.Currency]bool{},
+	}
+
+	for _, path := range paths {
+		err := res.readCSV(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, points := range res.prices {
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].ts.Before(points[j].ts)
+		})
+	}
+
+	return &res, nil
+}
+
+func (s *Source) readCSV(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = 3
+
+	// skip first line, containing header
+	_, err = csvReader.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	unknown := map[string]bool{}
+
+	for {
+		rec, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		ts, err := csvfile.ParseTime(strings.TrimSpace(rec[0]), time.Local, timeFormats...)
+		if err != nil {
+			return fmt.Errorf("%s: parsing %q failed: %s", path, rec[0], err)
+		}
+
+		symbol := strings.TrimSpace(rec[1])
+		cur, err := transaction.NewCurrency(symbol)
+		if err != nil {
+			if !unknown[symbol] {
+				log.Infof("%s: skipping prices of currency %q: %s", path, symbol, err)
+				unknown[symbol] = true
+			}
+			continue
+		}
+
+		price, err := math.ParseDecimal(rec[2])
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		s.prices[cur] = append(s.prices[cur], point{ts: ts, price: price})
+	}
+
+	return nil
+}
+
+// At returns the most recent price of currency at ts. If no price before
+// or at ts exists or it is older than MaxAge, false is returned.
+func (s *Source) At(currency transaction.Currency, ts time.Time) (math.Decimal, bool) {
+	points := s.prices[currency]
+
+	idx := sort.Search(len(points), func(i int) bool {
+		return points[i].ts.After(ts)
+	})
+	if idx == 0 {
+		return math.Decimal{}, false
+	}
+
+	p := points[idx-1]
+	if ts.Sub(p.ts) > MaxAge {
+		if !s.stale[currency] {
+			log.Warnf("price: the most recent price of %s at %s is from %s, it is older than %s and not used",
+				currency, ts.Format(time.RFC3339), p.ts.Format(time.RFC3339), MaxAge)
+			s.stale[currency] = true
+		}
+
+		return math.Decimal{}, false
+	}
+
+	return p.price, true
+}
+
+// AllAt returns the most recent prices at ts of all currencies.
+func (s *Source) AllAt(ts time.Time) map[transaction.Currency]math.Decimal {
+	res := map[transaction.Currency]math.Decimal{}
+
+	for cur := range s.prices {
+		if p, ok := s.At(cur, ts); ok {
+			res[cur] = p
+		}
+	}
+
+	return res
+}
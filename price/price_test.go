@@ -0,0 +1,58 @@
+package price
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fho/cryptotax/math"
+	"github.com/fho/cryptotax/transaction"
+)
+
+func TestAt(t *testing.T) {
+	src, err := ReadCSV("testdata/prices.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		currency transaction.Currency
+		ts       time.Time
+		want     string
+	}{
+		{name: "before the first price", currency: transaction.USD, ts: day.Add(-time.Hour)},
+		{name: "at the date", currency: transaction.USD, ts: day, want: "0.83"},
+		{name: "most recent", currency: transaction.USD, ts: time.Date(2021, 3, 2, 12, 0, 0, 0, time.UTC), want: "0.84"},
+		{name: "within the maximal age", currency: transaction.USD, ts: time.Date(2021, 3, 9, 12, 0, 0, 0, time.UTC), want: "0.84"},
+		{name: "older than the maximal age", currency: transaction.USD, ts: time.Date(2021, 3, 9, 12, 0, 1, 0, time.UTC)},
+		{name: "other currency", currency: transaction.BTC, ts: day, want: "41000.5"},
+		{name: "unknown currency", currency: transaction.ETH, ts: day},
+	}
+
+	for _, tt := range tests {
+		price, exist := src.At(tt.currency, tt.ts)
+
+		if exist != (len(tt.want) != 0) {
+			t.Errorf("%s: price exists is %v, want %v", tt.name, exist, !exist)
+			continue
+		}
+
+		if exist && price.Cmp(math.MustParseDecimal(tt.want)) != 0 {
+			t.Errorf("%s: price is %s, want %s", tt.name, price, tt.want)
+		}
+	}
+}
+
+func TestAllAt(t *testing.T) {
+	src, err := ReadCSV("testdata/prices.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prices := src.AllAt(time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC))
+	if len(prices) != 2 {
+		t.Errorf("got prices %v, want the prices of BTC and USD", prices)
+	}
+}
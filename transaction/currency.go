@@ -2,7 +2,9 @@ package transaction
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 )
 
 type Currency int
@@ -22,6 +24,7 @@ const (
 	XMR
 	XRP
 	ZEC
+	USD
 )
 
 var strToCurrency = map[string]Currency{
@@ -38,6 +41,7 @@ var strToCurrency = map[string]Currency{
 	"XMR":  XMR,
 	"XRP":  XRP,
 	"ZEC":  ZEC,
+	"USD":  USD,
 }
 
 var currencyToStr = map[Currency]string{
@@ -54,12 +58,49 @@ var currencyToStr = map[Currency]string{
 	XMR:  "XMR",
 	XRP:  "XRP",
 	ZEC:  "ZEC",
+	USD:  "USD",
 }
 
 var ErrUndefinedCurrency = errors.New("unsupported currency")
 
+// numPredefined is the number of currencies that are defined by the
+// constants, registered currencies are numbered after them
+var numPredefined = len(currencyToStr)
+
+// registry protects strToCurrency and currencyToStr, they are extended by
+// RegisterCurrency
+var registry sync.RWMutex
+
+// validSymbol returns true if s only consists of uppercase letters, digits
+// and the separators '.', '-' and '_'.
+func validSymbol(s string) bool {
+	if len(s) == 0 || len(s) > 20 {
+		return false
+	}
+
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		case c == '.' || c == '-' || c == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewCurrency returns the Currency of a ticker symbol, the symbol is case
+// insensitive.
+// ErrUndefinedCurrency is returned if the symbol is neither a predefined
+// currency nor registered with RegisterCurrency.
 func NewCurrency(currency string) (Currency, error) {
-	res, ok := strToCurrency[strings.ToUpper(currency)]
+	symbol := strings.ToUpper(strings.TrimSpace(currency))
+
+	registry.RLock()
+	res, ok := strToCurrency[symbol]
+	registry.RUnlock()
 	if !ok {
 		return CurrencyUndef, ErrUndefinedCurrency
 	}
@@ -67,11 +108,94 @@ func NewCurrency(currency string) (Currency, error) {
 	return res, nil
 }
 
+// RegisterCurrency returns the Currency of a ticker symbol like NewCurrency,
+// symbols that are not known yet are registered as new currency. It is used
+// by importers of exchange files that contain currencies that are not
+// predefined, e.g. tokens. ErrUndefinedCurrency is returned for invalid
+// symbols.
+func RegisterCurrency(currency string) (Currency, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(currency))
+
+	res, err := NewCurrency(symbol)
+	if err == nil {
+		return res, nil
+	}
+
+	if !validSymbol(symbol) {
+		return CurrencyUndef, ErrUndefinedCurrency
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if res, ok := strToCurrency[symbol]; ok {
+		return res, nil
+	}
+
+	res = Currency(len(currencyToStr) + 1)
+	strToCurrency[symbol] = res
+	currencyToStr[res] = symbol
+
+	return res, nil
+}
+
 func (c Currency) String() string {
+	registry.RLock()
 	res, ok := currencyToStr[c]
+	registry.RUnlock()
 	if !ok {
 		return "undefined"
 	}
 
 	return res
 }
+
+// Predefined returns true if c is one of the currencies that are defined by
+// the constants and was not registered later, e.g. for a token.
+func (c Currency) Predefined() bool {
+	return c > CurrencyUndef && int(c) <= numPredefined
+}
+
+// fiat contains the symbols of fiat currencies
+var fiat = map[string]bool{
+	"AUD": true,
+	"CAD": true,
+	"CHF": true,
+	"EUR": true,
+	"GBP": true,
+	"JPY": true,
+	"USD": true,
+}
+
+// IsFiat returns true if c is a fiat currency. Fiat currencies are not held
+// in lots, their value is converted to €.
+func (c Currency) IsFiat() bool {
+	return fiat[c.String()]
+}
+
+// MarshalText implements encoding.TextMarshaler. CurrencyUndef is
+// marshaled to an empty string.
+func (c Currency) MarshalText() ([]byte, error) {
+	if c == CurrencyUndef {
+		return []byte{}, nil
+	}
+
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Currency) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = CurrencyUndef
+		return nil
+	}
+
+	res, err := NewCurrency(string(text))
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, text)
+	}
+
+	*c = res
+
+	return nil
+}
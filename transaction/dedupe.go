@@ -0,0 +1,28 @@
+package transaction
+
+// Deduplicate removes transactions that have the same exchange, wallet and
+// ID as a previous transaction in records. It returns the remaining
+// transactions and the removed duplicates, the order is preserved.
+func Deduplicate(records []*Tx) (unique []*Tx, duplicates []*Tx) {
+	type key struct {
+		exchange string
+		wallet   string
+		id       string
+	}
+
+	seen := map[key]struct{}{}
+
+	for _, rec := range records {
+		k := key{exchange: rec.Exchange, wallet: rec.Wallet, id: rec.ID}
+
+		if _, exist := seen[k]; exist {
+			duplicates = append(duplicates, rec)
+			continue
+		}
+
+		seen[k] = struct{}{}
+		unique = append(unique, rec)
+	}
+
+	return unique, duplicates
+}
@@ -2,7 +2,6 @@ package transaction
 
 import (
 	"fmt"
-	"math/big"
 	"time"
 
 	"github.com/fho/cryptotax/math"
@@ -11,21 +10,52 @@ import (
 type Tx struct {
 	ID          string
 	Exchange    string
+	Wallet      string // account or wallet at the exchange
 	Timestamp   time.Time
 	Type        Type
 	PayCurrency Currency // the currency that is paid with
 	Currency    Currency // the curency that is bought
-	Quantity    *big.Float
-	SpotPrice   *big.Float
-	Fees        *big.Float
+	Quantity    math.Decimal
+	SpotPrice   math.Decimal
+	Fees        math.Decimal
+	// FeeCurrency is the currency of Fees, if it is undefined the fees
+	// are in PayCurrency
+	FeeCurrency Currency
+	// Value is the € market value of a trade between cryptocurrencies,
+	// it is zero if it is unknown
+	Value math.Decimal
+	// DonorAcquired and DonorCost are the acquisition date and the €
+	// cost basis of the Quantity of a received gift at its donor, the
+	// gift takes them over. DonorAcquired is zero if they are unknown.
+	DonorAcquired time.Time
+	DonorCost     math.Decimal
 }
 
 func (r *Tx) String() string {
-	return fmt.Sprintf("%s %s %s %s @ %s for %f %s + %s€ fees",
-		r.Timestamp.Format(time.RFC3339), r.Type, r.Quantity.String(), r.Currency,
-		r.Exchange, r.PriceNoFees(), r.PayCurrency, r.Fees.String())
+	return fmt.Sprintf("%s %s %s %s @ %s for %s %s + %s %s fees",
+		r.Timestamp.Format(time.RFC3339), r.Type, r.Quantity, r.Currency,
+		r.Account(), r.PriceNoFees(), r.PayCurrency, r.Fees, r.FeesIn())
 }
 
-func (r *Tx) PriceNoFees() *big.Float {
-	return math.NewFloat().Mul(r.Quantity, r.SpotPrice)
+// FeesIn returns the currency of the fees.
+func (r *Tx) FeesIn() Currency {
+	if r.FeeCurrency == CurrencyUndef {
+		return r.PayCurrency
+	}
+
+	return r.FeeCurrency
+}
+
+// Account returns the exchange and, if set, the wallet of the transaction
+// in the format exchange/wallet.
+func (r *Tx) Account() string {
+	if len(r.Wallet) == 0 {
+		return r.Exchange
+	}
+
+	return r.Exchange + "/" + r.Wallet
+}
+
+func (r *Tx) PriceNoFees() math.Decimal {
+	return r.Quantity.Mul(r.SpotPrice)
 }
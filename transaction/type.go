@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -11,16 +12,34 @@ const (
 	TypeUndef Type = iota
 	Buy
 	Sell
+	Deposit      // transfer from an own wallet
+	Withdrawal   // transfer to an own wallet
+	Income       // e.g. staking rewards, airdrops
+	GiftReceived // gift or inheritance
+	GiftSent
+	Lost // lost or stolen
 )
 
 var strToType = map[string]Type{
-	"buy":  Buy,
-	"sell": Sell,
+	"buy":           Buy,
+	"sell":          Sell,
+	"deposit":       Deposit,
+	"withdrawal":    Withdrawal,
+	"income":        Income,
+	"gift-received": GiftReceived,
+	"gift-sent":     GiftSent,
+	"lost":          Lost,
 }
 
 var typeToStr = map[Type]string{
-	Buy:  "buy",
-	Sell: "sell",
+	Buy:          "buy",
+	Sell:         "sell",
+	Deposit:      "deposit",
+	Withdrawal:   "withdrawal",
+	Income:       "income",
+	GiftReceived: "gift-received",
+	GiftSent:     "gift-sent",
+	Lost:         "lost",
 }
 
 var ErrUndefinedType = errors.New("unsupported transaction type")
@@ -42,3 +61,20 @@ func (t Type) String() string {
 
 	return res
 }
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Type) UnmarshalText(text []byte) error {
+	res, err := NewType(string(text))
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, text)
+	}
+
+	*t = res
+
+	return nil
+}